@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Preview the metrics of every configured request",
+	Long:  `Fetches every configured request once, prints each series its metrics would export with its labels and value, and flags the metric and label paths that matched nothing, without starting the HTTP server. Exits non-zero when a request fails or a path is missing. Discovery and probe templates are not checked.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+		if err != nil {
+			fatalf(exitConfig, "Error loading config file: %v", err)
+		}
+
+		ok := true
+		for _, metrics := range collector.NewManager(cfg).Check() {
+			if len(metrics) == 0 {
+				continue
+			}
+			fmt.Println(metrics[0].APIPath)
+			if err := metrics[0].Err; err != nil {
+				fmt.Printf("  ERR     %v\n", err)
+				ok = false
+				continue
+			}
+			for _, c := range metrics {
+				for _, s := range c.Series {
+					fmt.Printf("  %s\n", formatSeries(c.Name, s))
+				}
+				for _, path := range c.Missing {
+					fmt.Printf("  MISSING %s: %s\n", c.Name, path)
+					ok = false
+				}
+				if len(c.Series) == 0 && len(c.Missing) == 0 {
+					fmt.Printf("  NONE    %s: no series, e.g. filtered out or skipped\n", c.Name)
+				}
+			}
+		}
+		if !ok {
+			os.Exit(exitError)
+		}
+	},
+}
+
+// formatSeries formats s like the Prometheus text format does.
+func formatSeries(name string, s collector.CheckedSeries) string {
+	var labels []string
+	for _, k := range slices.Sorted(maps.Keys(s.Labels)) {
+		labels = append(labels, k+"="+strconv.Quote(s.Labels[k]))
+	}
+	value := strconv.FormatFloat(s.Value, 'g', -1, 64)
+	if s.Count > 0 {
+		value = fmt.Sprintf("sum %s over %d observations", value, s.Count)
+	}
+	return fmt.Sprintf("%s{%s} %s", name, strings.Join(labels, ","), value)
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var checkAuthCmd = &cobra.Command{
+	Use:   "check-auth",
+	Short: "Verify the GitHub token and print its identity",
+	Long:  `Calls /user (or /installation/repositories for GitHub App installation tokens) with the configured token, prints the identity, scopes and rate limits, and exits non-zero when GitHub rejects the token.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+			os.Exit(exitConfig)
+		}
+
+		info, err := collector.NewManager(cfg).CheckAuth()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Authentication failed: %v\n", err)
+			if collector.IsAuthError(err) {
+				os.Exit(exitAuth)
+			}
+			os.Exit(exitError)
+		}
+
+		fmt.Printf("API:        %s\n", cfg.GithubAPIURL)
+		if info.AppInstallation {
+			fmt.Println("Identity:   GitHub App installation")
+		} else {
+			fmt.Printf("Identity:   %s\n", info.Login)
+		}
+		scopes := strings.Join(info.Scopes, ", ")
+		if scopes == "" {
+			scopes = "(none reported, fine-grained or app token)"
+		}
+		fmt.Printf("Scopes:     %s\n", scopes)
+		fmt.Printf("Rate limit: %d/%d remaining, resets at %s\n", info.RateRemaining, info.RateLimit, info.RateReset.Format(time.RFC3339))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkAuthCmd)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix prefixes the environment variable equivalent of every flag,
+// e.g. GITHUB_EXPORTER_PORT for --port.
+const envPrefix = "GITHUB_EXPORTER_"
+
+var envReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// flagEnv returns the environment variable equivalent of the flag name,
+// e.g. GITHUB_EXPORTER_WEB_CONFIG_FILE for --web.config.file.
+func flagEnv(name string) string {
+	return envPrefix + strings.ToUpper(envReplacer.Replace(name))
+}
+
+// applyFlagEnv sets the flags of cmd that are not on the command line from
+// their environment variable equivalents, so containers can be configured
+// without a custom entrypoint. The command line wins.
+func applyFlagEnv(cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "help" {
+			return
+		}
+		value, ok := os.LookupEnv(flagEnv(f.Name))
+		if !ok {
+			return
+		}
+		if setErr := cmd.Flags().Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("%s: %w", flagEnv(f.Name), setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+// Exit codes, so that orchestrators can tell the failure classes apart.
+const (
+	exitError  = 1 // any other failure, e.g. endpoints failing verification
+	exitConfig = 2 // the config file, the web config or a flag is invalid
+	exitAuth   = 3 // GitHub rejected the token
+	exitBind   = 4 // the listening port could not be opened
+)
+
+// fatalf logs a message and exits with code.
+func fatalf(code int, format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(code)
+}
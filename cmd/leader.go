@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/leader"
+)
+
+// newElector builds the leader election configured by cfg, which switches
+// mgr between fetching and standby.
+func newElector(cfg config.LeaderElectionConfig, mgr *collector.Reloadable) (*leader.Elector, error) {
+	// Both durations were validated when loading the config.
+	duration, _ := time.ParseDuration(cfg.LeaseDuration)
+	retry, _ := time.ParseDuration(cfg.RetryPeriod)
+
+	var lock leader.Lock
+	switch cfg.Type {
+	case "kubernetes":
+		lease, err := leader.NewInClusterLease(cfg.Namespace, cfg.LeaseName, cfg.Identity, duration)
+		if err != nil {
+			return nil, err
+		}
+		lock = lease
+	default:
+		lock = leader.NewFileLock(cfg.Path)
+	}
+	return leader.NewElector(lock, retry, func(isLeader bool) {
+		mgr.SetStandby(!isLeader)
+	}), nil
+}
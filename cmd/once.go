@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/version"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pushGatewayURL string
+	pushJob        string
+	pushInstance   string
+)
+
+var onceCmd = &cobra.Command{
+	Use:   "once",
+	Short: "Collect metrics once and print them to stdout",
+	Long:  `Performs a single collection pass, including requests that have an interval and repository discovery, and writes the metrics in the Prometheus text format to stdout, e.g. for node_exporter's textfile collector, or pushes them to a Pushgateway.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+		if err != nil {
+			fatalf(exitConfig, "Error loading config file: %v", err)
+		}
+
+		mgr := collector.NewManager(cfg)
+		mgr.Prime()
+
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(mgr, mgr.SelfMetrics(), version.Collector(func() string { return cfg.Hash }))
+		families, err := reg.Gather()
+		mgr.SaveState()
+		if pushGatewayURL != "" {
+			// Pushing the gathered families rather than reg avoids fetching
+			// the requests without an interval a second time.
+			pusher := push.New(pushGatewayURL, pushJob).Gatherer(prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+				return families, nil
+			}))
+			if pushInstance != "" {
+				pusher = pusher.Grouping("instance", pushInstance)
+			}
+			if pushErr := pusher.Push(); pushErr != nil {
+				fatalf(exitError, "Error pushing metrics to %s: %v", pushGatewayURL, pushErr)
+			}
+		} else {
+			for _, mf := range families {
+				if _, err := expfmt.MetricFamilyToText(os.Stdout, mf); err != nil {
+					fatalf(exitError, "Error writing metrics: %v", err)
+				}
+			}
+		}
+		if err != nil {
+			fatalf(exitError, "Error gathering metrics: %v", err)
+		}
+	},
+}
+
+func init() {
+	onceCmd.Flags().StringVar(&pushGatewayURL, "push-gateway-url", "", "push the metrics to this Pushgateway instead of printing them")
+	onceCmd.Flags().StringVar(&pushJob, "push-job", "github-exporter", "job label of the pushed metrics")
+	onceCmd.Flags().StringVar(&pushInstance, "push-instance", "", "instance label of the pushed metrics, grouping them apart from other runs of the same job")
+	rootCmd.AddCommand(onceCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	queryPath    string
+	queryAPIPath string
+	queryFile    string
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Print what a metric path resolves to",
+	Long:  `Fetches --api-path with the configured token and base URL, or reads --file ("-" for stdin), and prints what --path resolves to, with the exporter's gjson modifiers. An api_path of the config is fetched with its settings, pagination and response_format included. Without --path the whole response is printed.`,
+	Example: `  github-exporter query --api-path /users/octocat/repos --path '#.stargazers_count'
+  github-exporter query --file repos.json --path '#(fork==false)#.name'`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (queryAPIPath == "") == (queryFile == "") {
+			fatalf(exitConfig, "Error: exactly one of --api-path and --file is required")
+		}
+
+		var body []byte
+		var err error
+		switch {
+		case queryFile == "-":
+			body, err = io.ReadAll(os.Stdin)
+		case queryFile != "":
+			body, err = os.ReadFile(queryFile)
+		default:
+			body, err = queryFetch()
+		}
+		if err != nil {
+			fatalf(exitError, "Error: %v", err)
+		}
+		if !gjson.ValidBytes(body) {
+			fatalf(exitError, "Error: the response is not valid JSON")
+		}
+
+		result := gjson.ParseBytes(body)
+		if queryPath != "" {
+			result = result.Get(queryPath)
+		}
+		if !result.Exists() {
+			fmt.Fprintf(os.Stderr, "%s matches nothing\n", queryPath)
+			os.Exit(exitError)
+		}
+		if !result.IsObject() && !result.IsArray() {
+			fmt.Println(result.String())
+			return
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, []byte(result.Raw), "", "  "); err != nil {
+			fmt.Println(result.Raw)
+			return
+		}
+		fmt.Println(out.String())
+	},
+}
+
+// queryFetch fetches queryAPIPath with the settings of the config.
+func queryFetch() ([]byte, error) {
+	cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+	if err != nil {
+		fatalf(exitConfig, "Error loading config file: %v", err)
+	}
+	req, err := cfg.Request(queryAPIPath)
+	if err != nil {
+		fatalf(exitConfig, "Error: %v", err)
+	}
+	return collector.NewManager(cfg).Fetch(req)
+}
+
+func init() {
+	queryCmd.Flags().StringVar(&queryPath, "path", "", "gjson path to evaluate, as in a metric's path")
+	queryCmd.Flags().StringVar(&queryAPIPath, "api-path", "", "API path or URL to fetch")
+	queryCmd.Flags().StringVar(&queryFile, "file", "", "JSON file to read instead of fetching, - for stdin")
+	rootCmd.AddCommand(queryCmd)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/remotewrite"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// runRemoteWrite pushes the registered metrics to the remote_write endpoint
+// of cfg until ctx is cancelled.
+func runRemoteWrite(ctx context.Context, cfg config.RemoteWriteConfig) {
+	// The interval was validated when loading the config.
+	interval, _ := time.ParseDuration(cfg.Interval)
+
+	header := make(http.Header)
+	for name, value := range cfg.Headers {
+		header.Set(name, value)
+	}
+	switch {
+	case cfg.BearerToken != "":
+		header.Set("Authorization", "Bearer "+cfg.BearerToken)
+	case cfg.Username != "" || cfg.Password != "":
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(cfg.Username+":"+cfg.Password)))
+	}
+
+	remotewrite.NewClient(cfg.URL, header, cfg.MaxRetries).Run(ctx, prometheus.DefaultGatherer, interval)
+}
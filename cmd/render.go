@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Print the effective config and exit",
+	Long:  `Loads the config file like the exporter does, executing its template, applying environment variable overrides, defaults, presets and target lists, and prints the result as YAML with tokens and secrets redacted.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config file: %v\n", err)
+			os.Exit(exitConfig)
+		}
+
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(cfg.Redacted()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+			os.Exit(exitError)
+		}
+		if err := enc.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
+			os.Exit(exitError)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renderCmd)
+}
@@ -2,61 +2,207 @@ package cmd
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/eleboucher/github-exporter/internal/collector"
 	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/logging"
+	"github.com/eleboucher/github-exporter/internal/version"
+	"github.com/eleboucher/github-exporter/internal/web"
+	"github.com/eleboucher/github-exporter/internal/webhook"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile    string
-	port       string
-	githubUser string
+	cfgFile       string
+	port          string
+	githubUser    string
+	webConfigFile string
+	verifyOnStart bool
+	failOnStartup bool
+	maxConcurrent int
+	logFormat     string
+	logLevel      string
+	strictConfig  bool
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "github-exporter",
 	Short: "A generic GitHub Prometheus exporter",
-	Long:  `Scrapes GitHub API endpoints based on a YAML configuration and exposes them as Prometheus metrics.`,
+	Long:  `Scrapes GitHub API endpoints based on a YAML configuration and exposes them as Prometheus metrics. Every flag can also be set with an environment variable, e.g. GITHUB_EXPORTER_PORT for --port or GITHUB_EXPORTER_WEB_CONFIG_FILE for --web.config.file; the command line takes precedence.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg, err := config.Load(cfgFile, githubUser)
+		cfg, err := loadConfig(cmd)
 		if err != nil {
-			log.Fatalf("Error loading config file: %v", err)
+			fatalf(exitConfig, "Error loading config file: %v", err)
 		}
 
+		if failOnStartup {
+			if _, err := collector.NewManager(cfg).CheckAuth(); err != nil {
+				code := exitError
+				if collector.IsAuthError(err) {
+					code = exitAuth
+				}
+				fatalf(code, "Error checking the GitHub token: %v", err)
+			}
+		}
+		if verifyOnStart && !verifyEndpoints(collector.NewManager(cfg)) {
+			fatalf(exitError, "Some configured endpoints failed verification")
+		}
+
+		if isService() {
+			if err := runService(func(ctx context.Context) { serve(ctx, cmd, cfg) }); err != nil {
+				fatalf(exitError, "Error running as a service: %v", err)
+			}
+			return
+		}
 		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 		defer stop()
-		log.Printf("Exporter listening on port %s", port)
+		serve(ctx, cmd, cfg)
+	},
+}
+
+// serve runs the exporter until ctx is cancelled.
+func serve(ctx context.Context, cmd *cobra.Command, cfg *config.Config) {
+	server, err := web.NewServer(http.DefaultServeMux, webConfigFile)
+	if err != nil {
+		fatalf(exitConfig, "Error: %v", err)
+	}
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		fatalf(exitBind, "Error listening on port %s: %v", port, err)
+	}
+	slog.Info("Starting github-exporter", "version", version.String(), "port", port)
 
-		go func() {
-			mgr := collector.NewManager(cfg)
+	mgr := collector.NewReloadable(cfg, func() (*config.Config, error) {
+		return loadConfig(cmd)
+	})
+	if cfg.LeaderElection.Type != "" {
+		elector, err := newElector(cfg.LeaderElection, mgr)
+		if err != nil {
+			fatalf(exitConfig, "Error setting up leader election: %v", err)
+		}
+		mgr.SetStandby(true)
+		prometheus.MustRegister(elector)
+		go elector.Run(ctx)
+	}
+	mgr.Start(ctx)
+	go reloadOnSignal(ctx, mgr)
 
-			prometheus.MustRegister(mgr)
-			http.Handle("/metrics", promhttp.Handler())
-			if err := http.ListenAndServe(":"+port, nil); err != nil {
-				log.Fatal(err)
-			}
-		}()
-		<-ctx.Done()
-		stop()
-	},
+	go func() {
+		prometheus.MustRegister(mgr, mgr.SelfMetrics(), version.Collector(mgr.ConfigHash))
+		http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "ok\n")
+		})
+		http.Handle("/readyz", mgr.ReadyHandler())
+		http.Handle("/-/reload", reloadHandler(mgr))
+		http.HandleFunc("/probe", mgr.ServeProbe)
+		http.Handle("/api/status", mgr.StatusHandler())
+		if cfg.Webhook.Secret != "" {
+			recv := webhook.NewReceiver(cfg.Webhook.Secret)
+			prometheus.MustRegister(recv)
+			http.Handle(cfg.Webhook.Path, recv)
+			slog.Info("Webhook receiver enabled", "path", cfg.Webhook.Path)
+		}
+		if cfg.RemoteWrite.URL != "" {
+			go runRemoteWrite(ctx, cfg.RemoteWrite)
+			slog.Info("Pushing metrics with remote write", "url", cfg.RemoteWrite.URL, "interval", cfg.RemoteWrite.Interval)
+		}
+		http.Handle("/metrics", promhttp.Handler())
+		http.Handle("/metrics/{group}", mgr.GroupHandler())
+		if err := web.Serve(server, ln); err != nil {
+			fatalf(exitError, "Error serving: %v", err)
+		}
+	}()
+	<-ctx.Done()
+	mgr.SaveState()
+}
+
+// loadConfig loads the config file and applies the flags that override it.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Flags().Changed("max-concurrent-requests") {
+		if maxConcurrent <= 0 {
+			return nil, fmt.Errorf("--max-concurrent-requests must be positive")
+		}
+		if cfg.Public {
+			return nil, fmt.Errorf("--max-concurrent-requests cannot be used in public mode")
+		}
+		cfg.MaxConcurrent = maxConcurrent
+	}
+	return cfg, nil
+}
+
+// reloadHandler reloads the configuration on POST /-/reload.
+func reloadHandler(mgr *collector.Reloadable) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := mgr.Reload(); err != nil {
+			http.Error(w, fmt.Sprintf("failed to reload config: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatal(err)
+		fatalf(exitError, "%v", err)
 	}
 }
 
+// setupLogging configures logging from --log.format and --log.level. The
+// older LOG_LEVEL environment variable is used when --log.level is not
+// set; as before, an invalid LOG_LEVEL falls back to info with a warning
+// rather than failing every command.
+func setupLogging(cmd *cobra.Command) error {
+	level := os.Getenv("LOG_LEVEL")
+	if cmd.Flags().Changed("log.level") || level == "" {
+		return logging.Setup(os.Stdout, logFormat, logLevel)
+	}
+	envErr := logging.Setup(os.Stdout, logFormat, level)
+	if envErr == nil {
+		return nil
+	}
+	if err := logging.Setup(os.Stdout, logFormat, "info"); err != nil {
+		return err
+	}
+	slog.Warn("Ignoring invalid LOG_LEVEL, using info", "err", envErr)
+	return nil
+}
+
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "config.yaml", "config file path")
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyFlagEnv(cmd); err != nil {
+			return err
+		}
+		return setupLogging(cmd)
+	}
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "config.yaml", "config file, directory or glob of files to merge")
+	_ = rootCmd.MarkPersistentFlagFilename("config", "yaml", "yml")
+	rootCmd.PersistentFlags().BoolVar(&strictConfig, "strict", true, "reject config keys that match no setting, --strict=false ignores them")
 	rootCmd.PersistentFlags().StringVar(&githubUser, "github-user", "", "GitHub username")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log.format", "json", "log format, json or text")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log.level", "info", "log level, debug, info, warn or error, optionally followed by per-component levels, e.g. info,http=debug,scheduler=warn (components: "+strings.Join(logging.Components, ", ")+")")
 	rootCmd.PersistentFlags().StringVar(&port, "port", "2112", "port to listen on")
+	rootCmd.Flags().IntVar(&maxConcurrent, "max-concurrent-requests", config.DefaultMaxConcurrent, "requests fetched in parallel, overrides max_concurrent_requests")
+	rootCmd.Flags().BoolVar(&failOnStartup, "fail-on-startup-errors", false, "exit at startup when the GitHub token cannot be verified, instead of starting and reporting the failures as metrics")
+	rootCmd.Flags().BoolVar(&verifyOnStart, "verify-endpoints-on-start", false, "fetch every configured endpoint once and exit if any fails")
+	rootCmd.Flags().StringVar(&webConfigFile, "web.config.file", "", "path to a web config file enabling TLS and basic auth")
 }
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the config file and exit",
+	Long:  `Prints a JSON Schema of the config file, for editors and linters. It describes the rendered config: a template action in a number or boolean setting is reported as a type mismatch.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.Schema()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing schema: %v\n", err)
+			os.Exit(exitError)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+)
+
+// isService reports whether the process was started by the Windows service
+// control manager, which never happens on this platform.
+func isService() bool { return false }
+
+func runService(serve func(ctx context.Context)) error {
+	return errors.New("services are only supported on Windows")
+}
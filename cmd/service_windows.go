@@ -0,0 +1,158 @@
+//go:build windows
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceName = "github-exporter"
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Manage the Windows service",
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the exporter as a Windows service started at boot",
+	Long:  `Registers the service with the current --config, --port and --github-user flags. The config path is made absolute, as services start in the system directory.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		configPath, err := filepath.Abs(cfgFile)
+		if err != nil {
+			return err
+		}
+		serviceArgs := []string{"--config", configPath, "--port", port}
+		if githubUser != "" {
+			serviceArgs = append(serviceArgs, "--github-user", githubUser)
+		}
+
+		m, err := mgr.Connect()
+		if err != nil {
+			return err
+		}
+		defer m.Disconnect()
+		if s, err := m.OpenService(serviceName); err == nil {
+			s.Close()
+			return fmt.Errorf("service %s already exists", serviceName)
+		}
+		s, err := m.CreateService(serviceName, exe, mgr.Config{
+			DisplayName: "GitHub Exporter",
+			Description: "Exposes GitHub API data as Prometheus metrics",
+			StartType:   mgr.StartAutomatic,
+		}, serviceArgs...)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		fmt.Printf("Installed service %s\n", serviceName)
+		return nil
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the Windows service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withService(func(s *mgr.Service) error { return s.Delete() })
+	},
+}
+
+var serviceStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the Windows service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withService(func(s *mgr.Service) error { return s.Start() })
+	},
+}
+
+var serviceStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the Windows service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withService(func(s *mgr.Service) error {
+			_, err := s.Control(svc.Stop)
+			return err
+		})
+	},
+}
+
+// withService calls fn with the installed service.
+func withService(fn func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", serviceName, err)
+	}
+	defer s.Close()
+	return fn(s)
+}
+
+// isService reports whether the process was started by the Windows service
+// control manager.
+func isService() bool {
+	ok, err := svc.IsWindowsService()
+	if err != nil {
+		slog.Error("Failed to detect the Windows service environment", "err", err)
+	}
+	return ok
+}
+
+// runService runs serve until the service control manager asks the service
+// to stop.
+func runService(serve func(ctx context.Context)) error {
+	return svc.Run(serviceName, &service{serve: serve})
+}
+
+type service struct {
+	serve func(ctx context.Context)
+}
+
+func (s *service) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.serve(ctx)
+		close(done)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for req := range requests {
+		switch req.Cmd {
+		case svc.Interrogate:
+			status <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			cancel()
+			<-done
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+func init() {
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd, serviceStartCmd, serviceStopCmd)
+	rootCmd.AddCommand(serviceCmd)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+)
+
+// reloadOnSignal only points to the reload endpoint: there is no SIGHUP on
+// this platform.
+func reloadOnSignal(ctx context.Context, mgr *collector.Reloadable) {
+	slog.Info("Reload the config with POST /-/reload, SIGHUP is not available on this platform")
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+)
+
+// reloadOnSignal reloads the configuration every time the process receives
+// SIGHUP.
+func reloadOnSignal(ctx context.Context, mgr *collector.Reloadable) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("Received SIGHUP, reloading config")
+			_ = mgr.Reload()
+		}
+	}
+}
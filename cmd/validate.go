@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the config file and exit",
+	Long:  `Renders the config file template and the files it includes, checks its YAML, metric names, aggregates and value types, and reports every problem with its line number.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if validateOutput != "text" && validateOutput != "json" {
+			fmt.Fprintf(os.Stderr, "Error: unknown --output %q, expected text or json\n", validateOutput)
+			os.Exit(exitConfig)
+		}
+		problems := config.Validate(cfgFile, githubUser, strictConfig)
+		if validateOutput == "json" {
+			writeValidateJSON(problems)
+			return
+		}
+
+		for _, p := range problems {
+			file := cmp.Or(p.File, cfgFile)
+			if p.Line > 0 {
+				fmt.Fprintf(os.Stderr, "%s:%d: %s\n", file, p.Line, p.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", file, p.Message)
+			}
+		}
+		if len(problems) > 0 {
+			os.Exit(exitConfig)
+		}
+		fmt.Printf("%s is valid\n", cfgFile)
+	},
+}
+
+var validateOutput string
+
+// validateResult is the JSON output of validate.
+type validateResult struct {
+	Valid    bool             `json:"valid"`
+	Problems []config.Problem `json:"problems"`
+}
+
+// writeValidateJSON prints problems as a validateResult and exits non-zero
+// when there are any. Problems of the main config file are reported with
+// its path.
+func writeValidateJSON(problems []config.Problem) {
+	res := validateResult{Valid: len(problems) == 0, Problems: []config.Problem{}}
+	for _, p := range problems {
+		p.File = cmp.Or(p.File, cfgFile)
+		res.Problems = append(res.Problems, p)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(res); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing results: %v\n", err)
+		os.Exit(exitError)
+	}
+	if !res.Valid {
+		os.Exit(exitConfig)
+	}
+}
+
+func init() {
+	validateCmd.Flags().StringVarP(&validateOutput, "output", "o", "text", "output format, text or json")
+	_ = validateCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.AddCommand(validateCmd)
+}
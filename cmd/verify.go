@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/collector"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Fetch every configured endpoint once and report failures",
+	Long:  `Fetches every configured api_path once and reports the ones GitHub answers with an error such as 404 or 403, so misconfigured paths are caught at deploy time rather than discovered as missing metrics.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load(cfgFile, githubUser, strictConfig)
+		if err != nil {
+			fatalf(exitConfig, "Error loading config file: %v", err)
+		}
+		if !verifyEndpoints(collector.NewManager(cfg)) {
+			os.Exit(exitError)
+		}
+	},
+}
+
+// verifyEndpoints prints the outcome of every configured endpoint and
+// reports whether they all succeeded.
+func verifyEndpoints(mgr *collector.Manager) bool {
+	ok := true
+	for _, c := range mgr.VerifyEndpoints() {
+		if c.Err == nil {
+			fmt.Printf("OK   %s\n", c.APIPath)
+			continue
+		}
+		ok = false
+		status := "ERR"
+		if c.Status != 0 {
+			status = fmt.Sprint(c.Status)
+		}
+		fmt.Printf("%-4s %s: %v\n", status, c.APIPath, c.Err)
+	}
+	return ok
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
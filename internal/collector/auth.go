@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// AuthInfo describes the identity behind the configured token.
+type AuthInfo struct {
+	Login           string   // user login, or "installation" for GitHub App installation tokens
+	Scopes          []string // classic token scopes, empty for fine-grained tokens
+	RateLimit       int
+	RateRemaining   int
+	RateReset       time.Time
+	AppInstallation bool
+}
+
+// CheckAuth calls /user to verify the token. Installation tokens of GitHub
+// Apps cannot read /user, so a 403 falls back to /installation/repositories.
+func (m *Manager) CheckAuth() (*AuthInfo, error) {
+	user := config.RequestConfig{ApiPath: "/user"}
+	resp, err := m.fetchURL(context.Background(), user, m.requestURL(user))
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusForbidden {
+		req := config.RequestConfig{ApiPath: "/installation/repositories?per_page=1"}
+		if resp, err = m.fetchURL(context.Background(), req, m.requestURL(req)); err == nil {
+			info := authInfo(resp.header)
+			info.Login = "installation"
+			info.AppInstallation = true
+			return info, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := authInfo(resp.header)
+	info.Login = gjson.GetBytes(resp.body, "login").String()
+	return info, nil
+}
+
+// IsAuthError reports whether err is GitHub rejecting the token, as opposed
+// to GitHub being unreachable.
+func IsAuthError(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && (statusErr.code == http.StatusUnauthorized || statusErr.code == http.StatusForbidden)
+}
+
+// authInfo reads the scopes and rate limits GitHub reports in every
+// response.
+func authInfo(header http.Header) *AuthInfo {
+	info := &AuthInfo{}
+	for _, scope := range strings.Split(header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			info.Scopes = append(info.Scopes, scope)
+		}
+	}
+	info.RateLimit, _ = strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	info.RateRemaining, _ = strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		info.RateReset = time.Unix(reset, 0)
+	}
+	return info
+}
@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestCheckAuth_User(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("Expected /user, got %s", r.URL.Path)
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"login": "octocat"}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	info, err := m.CheckAuth()
+	if err != nil {
+		t.Fatalf("CheckAuth failed: %v", err)
+	}
+	if info.Login != "octocat" {
+		t.Errorf("Expected login octocat, got %q", info.Login)
+	}
+	if !slices.Equal(info.Scopes, []string{"repo", "read:org"}) {
+		t.Errorf("Expected scopes [repo read:org], got %v", info.Scopes)
+	}
+	if info.RateLimit != 5000 || info.RateRemaining != 4990 {
+		t.Errorf("Expected rate limit 4990/5000, got %d/%d", info.RateRemaining, info.RateLimit)
+	}
+	if info.RateReset.Unix() != 1700000000 {
+		t.Errorf("Expected reset 1700000000, got %d", info.RateReset.Unix())
+	}
+}
+
+func TestCheckAuth_AppInstallation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/user" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"total_count": 3, "repositories": []}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	info, err := m.CheckAuth()
+	if err != nil {
+		t.Fatalf("CheckAuth failed: %v", err)
+	}
+	if !info.AppInstallation {
+		t.Error("Expected an app installation token")
+	}
+}
+
+func TestCheckAuth_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	if _, err := m.CheckAuth(); err == nil {
+		t.Error("Expected error for a rejected token")
+	}
+}
@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// MetricCheck is what a metric of a configured request resolved to in a
+// dry run.
+type MetricCheck struct {
+	APIPath string
+	Name    string
+	Series  []CheckedSeries
+	Missing []string // value and label paths that matched nothing
+	Err     error    // the request failed, Series and Missing are empty
+}
+
+// CheckedSeries is a series a metric would export.
+type CheckedSeries struct {
+	Labels map[string]string
+	Value  float64
+	Count  uint64 // observations of a histogram, whose Value is their sum
+}
+
+// Check fetches every configured request once, like VerifyEndpoints, and
+// reports the series each of its metrics would export along with the paths
+// that matched nothing, which would otherwise be exported as 0 or skipped
+// silently. Counters and deltas take the fetch as an observation, so the
+// Manager is meant for the dry run only.
+func (m *Manager) Check() [][]MetricCheck {
+	m.mu.RLock()
+	jobs := append([]*job(nil), m.jobs...)
+	m.mu.RUnlock()
+
+	checks := make([][]MetricCheck, len(jobs))
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.semaphore <- struct{}{}
+			defer func() { <-m.semaphore }()
+			checks[i] = m.checkJob(j)
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+func (m *Manager) checkJob(j *job) []MetricCheck {
+	checks := make([]MetricCheck, len(j.req.Metrics))
+	for i, metric := range j.req.Metrics {
+		checks[i] = MetricCheck{APIPath: j.req.ApiPath, Name: metric.Name}
+	}
+
+	body, err := m.fetchBody(context.Background(), j.req)
+	if err == nil {
+		body, err = decodeBody(j.req, body)
+	}
+	var samples []sample
+	var expected *expectedStatusError
+	switch {
+	case errors.As(err, &expected):
+		samples = m.statusSamples(j, expected.code, time.Now())
+	case err != nil:
+		for i := range checks {
+			checks[i].Err = err
+		}
+		return checks
+	case len(bytes.TrimSpace(body)) == 0 && j.req.OnEmpty != config.EmptyZero:
+		for i := range checks {
+			checks[i].Missing = []string{"empty response"}
+		}
+		return checks
+	default:
+		samples = m.parseSamples(j, string(body))
+		for i, metric := range j.req.Metrics {
+			checks[i].Missing = missingPaths(string(body), metric)
+		}
+	}
+
+	for _, s := range samples {
+		for i := range checks {
+			if checks[i].Name != s.info.Config.Name {
+				continue
+			}
+			series := CheckedSeries{Labels: make(map[string]string), Value: s.value}
+			for k, key := range s.info.LabelKeys {
+				series.Labels[key] = s.labelValues[k]
+			}
+			if s.histogram != nil {
+				series.Value, series.Count = s.histogram.sum, s.histogram.count
+			}
+			checks[i].Series = append(checks[i].Series, series)
+		}
+	}
+	return checks
+}
+
+// missingPaths returns the paths of metric that match nothing in body.
+// Paths evaluated on array elements, those of grouped metrics and
+// relative labels, are not checked.
+func missingPaths(body string, metric config.MetricConfig) []string {
+	var paths []string
+	if metric.Expr == "" && metric.GroupBy == "" && metric.Path != "" && missing(query(body, metric)) {
+		paths = append(paths, fmt.Sprintf("path %q", metric.Path))
+	}
+	if metric.RelativeLabels {
+		return paths
+	}
+	for _, key := range slices.Sorted(maps.Keys(metric.Labels)) {
+		if !gjson.Get(body, metric.Labels[key]).Exists() {
+			paths = append(paths, fmt.Sprintf("label %s path %q", key, metric.Labels[key]))
+		}
+	}
+	return paths
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, err := io.WriteString(w, `{"stargazers_count": 3, "owner": {"login": "acme"}}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{ApiPath: "/repos/acme/app", Metrics: []config.MetricConfig{
+				{Name: "github_stars", Path: "stargazers_count", Help: "Stars", Labels: map[string]string{"owner": "owner.login"}},
+				{Name: "github_forks", Path: "forks_count", Help: "Forks", Labels: map[string]string{"team": "owner.team"}},
+			}},
+			{ApiPath: "/repos/acme/typo", Metrics: []config.MetricConfig{
+				{Name: "github_watchers", Path: "watchers_count", Help: "Watchers"},
+			}},
+		},
+	}
+
+	checks := NewManager(cfg).Check()
+	if len(checks) != 2 || len(checks[0]) != 2 || len(checks[1]) != 1 {
+		t.Fatalf("Expected checks for 2 requests with 2 and 1 metrics, got %+v", checks)
+	}
+
+	stars := checks[0][0]
+	if len(stars.Missing) != 0 || stars.Err != nil {
+		t.Errorf("Expected github_stars to resolve, got missing %v, err %v", stars.Missing, stars.Err)
+	}
+	if len(stars.Series) != 1 || stars.Series[0].Value != 3 || stars.Series[0].Labels["owner"] != "acme" {
+		t.Errorf("Expected github_stars{owner=\"acme\"} 3, got %+v", stars.Series)
+	}
+
+	forks := checks[0][1]
+	if len(forks.Missing) != 2 {
+		t.Errorf("Expected the value and label paths of github_forks to be missing, got %v", forks.Missing)
+	}
+	if len(forks.Series) != 1 || forks.Series[0].Value != 0 {
+		t.Errorf("Expected github_forks to be exported as 0, got %+v", forks.Series)
+	}
+
+	if checks[1][0].Err == nil {
+		t.Error("Expected an error for the request answered with 404")
+	}
+}
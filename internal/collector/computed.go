@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// computedMetric is a ratio of two metrics, evaluated on every Collect.
+type computedMetric struct {
+	cfg  config.ComputedConfig
+	info *MetricInfo
+}
+
+// initComputed registers the computed metrics. Their labels are the labels
+// of the numerator, without api_path since series of different requests are
+// combined.
+func (m *Manager) initComputed() {
+	for _, cm := range m.cfg.Computed {
+		num, ok := m.metrics[cm.Numerator]
+		if !ok {
+			logger.Error("Unknown numerator for computed metric", "name", cm.Name, "numerator", cm.Numerator)
+			continue
+		}
+		labelKeys := withoutAPIPath(num.LabelKeys)
+		m.computed = append(m.computed, &computedMetric{
+			cfg: cm,
+			info: &MetricInfo{
+				Desc:      prometheus.NewDesc(m.cfg.MetricPrefix+cm.Name, cm.Help, labelKeys, m.constLabels(config.MetricConfig{}, labelKeys)),
+				LabelKeys: labelKeys,
+				Config:    config.MetricConfig{Name: cm.Name, Help: cm.Help},
+			},
+		})
+	}
+}
+
+// computeSamples derives the computed metrics from the samples of a
+// collection. Numerator and denominator series sharing the same label
+// values are summed before dividing; a zero denominator yields no series.
+func (m *Manager) computeSamples(samples []sample) []sample {
+	var out []sample
+	for _, cm := range m.computed {
+		num := sumByLabels(samples, cm.cfg.Numerator, cm.info.LabelKeys)
+		den := sumByLabels(samples, cm.cfg.Denominator, cm.info.LabelKeys)
+		for key, n := range num {
+			d, ok := den[key]
+			if !ok || d.value == 0 {
+				continue
+			}
+			out = append(out, sample{
+				info:        cm.info,
+				labelValues: n.labelValues,
+				value:       n.value / d.value,
+			})
+		}
+	}
+	return out
+}
+
+// sumByLabels sums the samples of metric name grouped by the values of
+// labelKeys.
+func sumByLabels(samples []sample, name string, labelKeys []string) map[string]*sample {
+	sums := make(map[string]*sample)
+	for _, s := range samples {
+		if s.info.Config.Name != name {
+			continue
+		}
+		values := labelValuesFor(s, labelKeys)
+		key := strings.Join(values, "\xff")
+		if sum, ok := sums[key]; ok {
+			sum.value += s.value
+			continue
+		}
+		sums[key] = &sample{labelValues: values, value: s.value}
+	}
+	return sums
+}
+
+// withoutAPIPath returns labelKeys without api_path, for matching series
+// produced by different requests.
+func withoutAPIPath(labelKeys []string) []string {
+	var keys []string
+	for _, k := range labelKeys {
+		if k != "api_path" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// labelValuesFor returns the values of labelKeys in s, empty for labels s
+// does not have.
+func labelValuesFor(s sample, labelKeys []string) []string {
+	values := make([]string, len(labelKeys))
+	for i, key := range labelKeys {
+		for j, k := range s.info.LabelKeys {
+			if k == key {
+				values[i] = s.labelValues[j]
+				break
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollect_ComputedRatio(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		var body string
+		switch r.URL.Query().Get("q") {
+		case "repo:acme/app is:pr is:merged":
+			body = `{"total_count": 30}`
+		case "repo:acme/app is:pr is:closed":
+			body = `{"total_count": 40}`
+		default:
+			body = `{"total_count": 0}`
+		}
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	metric := func(name string) []config.MetricConfig {
+		return []config.MetricConfig{{
+			Name:   name,
+			Path:   "total_count",
+			Help:   name,
+			Labels: map[string]string{"repo": `!"acme/app"`},
+		}}
+	}
+	cfg := &config.Config{
+		GithubAPIURL:    server.URL,
+		SearchRateLimit: 600,
+		Requests: []config.RequestConfig{
+			{ApiPath: "/search/issues?q=repo:acme/app+is:pr+is:merged", Metrics: metric("github_merged")},
+			{ApiPath: "/search/issues?q=repo:acme/app+is:pr+is:closed", Metrics: metric("github_closed")},
+			{ApiPath: "/search/issues?q=repo:acme/app+is:issue", Metrics: metric("github_issues")},
+		},
+		Computed: []config.ComputedConfig{
+			{Name: "github_merge_ratio", Help: "Merge ratio", Numerator: "github_merged", Denominator: "github_closed"},
+			{Name: "github_never", Help: "Zero denominator", Numerator: "github_merged", Denominator: "github_issues"},
+		},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_merge_ratio Merge ratio
+# TYPE github_merge_ratio gauge
+github_merge_ratio{repo="acme/app"} 0.75
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_merge_ratio", "github_never"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSumByLabels(t *testing.T) {
+	info := &MetricInfo{
+		LabelKeys: []string{"api_path", "repo"},
+		Config:    config.MetricConfig{Name: "github_runs"},
+	}
+	samples := []sample{
+		{info: info, labelValues: []string{"/a", "acme/app"}, value: 2},
+		{info: info, labelValues: []string{"/b", "acme/app"}, value: 3},
+		{info: info, labelValues: []string{"/c", "acme/lib"}, value: 4},
+	}
+
+	sums := sumByLabels(samples, "github_runs", []string{"repo"})
+	if got := sums["acme/app"].value; got != 5 {
+		t.Errorf("Expected 5 for acme/app, got %f", got)
+	}
+	if got := sums["acme/lib"].value; got != 4 {
+		t.Errorf("Expected 4 for acme/lib, got %f", got)
+	}
+}
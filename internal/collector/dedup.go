@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// fetchGroup shares the response of identical requests, so endpoints
+// declared by several requests (e.g. metrics organized by topic) are
+// fetched once. A group created for a collection cycle keeps every result
+// until the cycle ends; the Manager's long-lived group only merges fetches
+// that are in flight at the same time.
+type fetchGroup struct {
+	keep bool
+
+	mu    sync.Mutex
+	calls map[string]*fetchCall
+}
+
+type fetchCall struct {
+	done chan struct{}
+	body []byte
+	err  error
+}
+
+func newFetchGroup(keep bool) *fetchGroup {
+	return &fetchGroup{keep: keep, calls: make(map[string]*fetchCall)}
+}
+
+// do calls fetch unless a request with the same key is in flight or, for a
+// cycle group, already done; shared reports whether the result came from
+// another caller.
+func (g *fetchGroup) do(key string, fetch func() ([]byte, error)) (body []byte, shared bool, err error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-c.done
+		return c.body, true, c.err
+	}
+	c := &fetchCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.body, c.err = fetch()
+	close(c.done)
+	if !g.keep {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+	}
+	return c.body, false, c.err
+}
+
+// fetchKey identifies what a request sends and how its response is read,
+// ignoring its metrics, labels and schedule.
+func (m *Manager) fetchKey(req config.RequestConfig) string {
+	key, err := json.Marshal(struct {
+		Method, URL, Body, Token string
+		CompressBody             bool
+		ResponseFormat           config.ResponseFormat
+		ExpectStatus             []int
+		Paginate                 *config.PaginateConfig
+		GraphQLPaginate          *config.GraphQLPaginateConfig
+		FanOut                   *config.FanOutConfig
+	}{
+		req.Method, m.requestURL(req), req.Body, req.Token,
+		req.CompressBody, req.ResponseFormat, req.ExpectStatus,
+		req.Paginate, req.GraphQLPaginate, req.FanOut,
+	})
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
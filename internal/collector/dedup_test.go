@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollect_DeduplicatesIdenticalRequests(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"stargazers_count": 5, "forks_count": 2}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{ApiPath: "/repos/acme/app", Metrics: []config.MetricConfig{{Name: "github_stars", Path: "stargazers_count", Help: "Stars"}}},
+			{ApiPath: "/repos/acme/app", Metrics: []config.MetricConfig{{Name: "github_forks", Path: "forks_count", Help: "Forks"}}},
+			{ApiPath: "/repos/acme/app", Method: "POST", Body: `{}`, Metrics: []config.MetricConfig{{Name: "github_posted", Path: "forks_count", Help: "Different method"}}},
+		},
+	}
+
+	m := NewManager(cfg)
+	if got := testutil.CollectAndCount(m, "github_stars", "github_forks", "github_posted"); got != 3 {
+		t.Errorf("Expected 3 series, got %d", got)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Expected 2 API calls, got %d", got)
+	}
+
+	// The next collection fetches again.
+	testutil.CollectAndCount(m)
+	if got := hits.Load(); got != 4 {
+		t.Errorf("Expected 4 API calls after a second collection, got %d", got)
+	}
+}
+
+func TestFetchGroup_InFlightOnly(t *testing.T) {
+	g := newFetchGroup(false)
+	calls := 0
+	fetch := func() ([]byte, error) {
+		calls++
+		return []byte("{}"), nil
+	}
+
+	if _, shared, _ := g.do("key", fetch); shared {
+		t.Error("Expected the first call not to be shared")
+	}
+	if _, shared, _ := g.do("key", fetch); shared {
+		t.Error("Expected a completed call not to be reused outside a cycle")
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 fetches, got %d", calls)
+	}
+}
@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/schedule"
+	"github.com/tidwall/gjson"
+)
+
+const discoveryPageSize = 100
+
+// runDiscovery periodically enumerates the repositories of d.Org and
+// replaces the discovered jobs. Background fetchers of the previous set are
+// stopped when a new set is installed; on failure the previous set is kept.
+func (m *Manager) runDiscovery(ctx context.Context, idx int, d config.DiscoveryConfig) {
+	refresh, err := schedule.Parse(d.RefreshInterval)
+	if err != nil {
+		schedulerLogger.Error("Invalid discovery refresh interval", "org", d.Org, "err", err)
+		return
+	}
+
+	stopJobs := func() {}
+	defer func() { stopJobs() }()
+
+	for {
+		jobs, err := m.discover(ctx, d)
+		if err != nil {
+			logger.Error("Repository discovery failed, keeping previous set", "org", d.Org, "err", err)
+		} else {
+			stopJobs()
+
+			m.mu.Lock()
+			previous := m.discovered[idx]
+			m.discovered[idx] = jobs
+			m.mu.Unlock()
+			m.forgetRemoved(previous, jobs)
+
+			stopJobs = m.startJobs(ctx, jobs)
+			logger.Info("Discovered repositories", "org", d.Org, "jobs", len(jobs))
+		}
+
+		if !sleepUntilNext(ctx, refresh) {
+			return
+		}
+	}
+}
+
+// startJobs starts the background fetchers of the scheduled jobs. They run
+// until ctx is cancelled or the returned function is called.
+func (m *Manager) startJobs(ctx context.Context, jobs []*job) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	for _, j := range jobs {
+		if j.schedule != nil {
+			go m.runScheduled(ctx, j)
+		}
+	}
+	return cancel
+}
+
+// forgetRemoved drops the self-metrics of jobs that disappeared from the
+// discovered set, e.g. deleted repositories.
+func (m *Manager) forgetRemoved(previous, current []*job) {
+	kept := make(map[string]bool, len(current))
+	for _, j := range current {
+		kept[j.req.ApiPath] = true
+	}
+	for _, j := range previous {
+		if !kept[j.req.ApiPath] {
+			m.self.forget(j.req)
+		}
+	}
+}
+
+// discover lists the repositories of d.Org and expands the request
+// templates for each of them.
+func (m *Manager) discover(ctx context.Context, d config.DiscoveryConfig) ([]*job, error) {
+	repos, err := m.listOrgRepos(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*job
+	for _, tmpl := range d.Requests {
+		if !m.supported(tmpl) {
+			logger.Info("Skipping discovery template, server version too old", append(logArgs(tmpl), "min_server_version", tmpl.MinServerVersion)...)
+			continue
+		}
+		for _, repo := range repos {
+			req, err := expandTemplate(tmpl, map[string]string{"Repo": repo})
+			if err != nil {
+				return nil, err
+			}
+			jobs = append(jobs, newJob(req, map[string]string{"repo": repo}))
+		}
+	}
+	return jobs, nil
+}
+
+func (m *Manager) listOrgRepos(ctx context.Context, d config.DiscoveryConfig) ([]string, error) {
+	inst := m.cfg.Instances[d.Instance]
+	var repos []string
+	for page := 1; ; page++ {
+		body, err := m.fetch(ctx, config.RequestConfig{
+			ApiPath: fmt.Sprintf("/orgs/%s/repos?per_page=%d&page=%d", url.PathEscape(d.Org), discoveryPageSize, page),
+			BaseURL: inst.APIURL,
+			Token:   inst.Token,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result := gjson.ParseBytes(body)
+		if !result.IsArray() {
+			return nil, fmt.Errorf("unexpected repository list for org %s", d.Org)
+		}
+		items := result.Array()
+		for _, r := range items {
+			if d.SkipArchived && r.Get("archived").Bool() {
+				continue
+			}
+			if d.SkipForks && r.Get("fork").Bool() {
+				continue
+			}
+			repos = append(repos, r.Get("full_name").String())
+		}
+		if len(items) < discoveryPageSize {
+			return repos, nil
+		}
+	}
+}
+
+// expandTemplate renders the references to data, e.g. {{ .Repo }}, in the
+// api_path, body, name and query_params of a request template. Time
+// functions are kept for expandTime.
+func expandTemplate(tmpl config.RequestConfig, data map[string]string) (config.RequestConfig, error) {
+	return config.ExpandRequest(tmpl, data, config.DeferredTimeFuncs())
+}
+
+// expandTime evaluates the time functions of a request, e.g.
+// {{ daysAgo 30 }}, right before it is fetched.
+func expandTime(req config.RequestConfig, now time.Time) (config.RequestConfig, error) {
+	templated := strings.Contains(req.ApiPath, "{{") || strings.Contains(req.Body, "{{")
+	for _, v := range req.QueryParams {
+		templated = templated || strings.Contains(v, "{{")
+	}
+	if !templated {
+		return req, nil
+	}
+	return config.ExpandRequest(req, nil, config.TimeFuncs(now))
+}
@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func newOrgServer(t *testing.T, repoCount int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		switch {
+		case r.URL.Path == "/orgs/acme/repos":
+			page := r.URL.Query().Get("page")
+			start := 0
+			if page == "2" {
+				start = discoveryPageSize
+			}
+			var repos []string
+			for i := start; i < repoCount && i < start+discoveryPageSize; i++ {
+				repos = append(repos, fmt.Sprintf(`{"full_name": "acme/repo-%d", "archived": %t}`, i, i == 1))
+			}
+			body = "[" + strings.Join(repos, ",") + "]"
+		case strings.HasPrefix(r.URL.Path, "/repos/acme/"):
+			body = `{"stargazers_count": 5}`
+		default:
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+}
+
+func TestDiscover_ExpandsTemplatesPerRepo(t *testing.T) {
+	server := newOrgServer(t, 3)
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Discovery: []config.DiscoveryConfig{
+			{
+				Org:          "acme",
+				SkipArchived: true,
+				Requests: []config.RequestConfig{
+					{
+						ApiPath: "/repos/{{ .Repo }}",
+						Metrics: []config.MetricConfig{
+							{
+								Name: "github_repo_stars",
+								Path: "stargazers_count",
+								Help: "Stars",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	jobs, err := m.discover(context.Background(), cfg.Discovery[0])
+	if err != nil {
+		t.Fatalf("Failed to discover: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("Expected 2 jobs (archived repo skipped), got %d", len(jobs))
+	}
+	if jobs[1].req.ApiPath != "/repos/acme/repo-2" {
+		t.Errorf("Expected '/repos/acme/repo-2', got '%s'", jobs[1].req.ApiPath)
+	}
+	m.discovered[0] = jobs
+
+	ch := make(chan prometheus.Metric, 10)
+	m.Collect(ch)
+	close(ch)
+
+	repos := make(map[string]float64)
+	for metric := range ch {
+		var metricDTO dto.Metric
+		if err := metric.Write(&metricDTO); err != nil {
+			t.Errorf("Failed to write metric: %v", err)
+		}
+		for _, label := range metricDTO.GetLabel() {
+			if label.GetName() == "repo" {
+				repos[label.GetValue()] = metricDTO.GetGauge().GetValue()
+			}
+		}
+	}
+
+	if len(repos) != 2 || repos["acme/repo-0"] != 5 || repos["acme/repo-2"] != 5 {
+		t.Errorf("Unexpected discovered series: %v", repos)
+	}
+}
+
+func TestListOrgRepos_Paginates(t *testing.T) {
+	server := newOrgServer(t, discoveryPageSize+5)
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	repos, err := m.listOrgRepos(context.Background(), config.DiscoveryConfig{Org: "acme"})
+	if err != nil {
+		t.Fatalf("Failed to list repositories: %v", err)
+	}
+
+	if len(repos) != discoveryPageSize+5 {
+		t.Errorf("Expected %d repositories, got %d", discoveryPageSize+5, len(repos))
+	}
+}
+
+func TestExpandTemplate_Name(t *testing.T) {
+	req, err := expandTemplate(config.RequestConfig{Name: "stars {{ .Repo }}", ApiPath: "/repos/{{ .Repo }}"}, map[string]string{"Repo": "acme/app"})
+	if err != nil {
+		t.Fatalf("Failed to expand template: %v", err)
+	}
+	if req.Name != "stars acme/app" {
+		t.Errorf("Expected the name to be expanded, got %q", req.Name)
+	}
+}
+
+func TestExpandTemplate_QueryParams(t *testing.T) {
+	tmpl := config.RequestConfig{ApiPath: "/search/issues", QueryParams: map[string]string{"q": "repo:{{ .Repo }} is:open"}}
+	req, err := expandTemplate(tmpl, map[string]string{"Repo": "acme/app"})
+	if err != nil {
+		t.Fatalf("Failed to expand template: %v", err)
+	}
+	if got := req.QueryParams["q"]; got != "repo:acme/app is:open" {
+		t.Errorf("Expected the query params to be expanded, got %q", got)
+	}
+	if got := tmpl.QueryParams["q"]; got != "repo:{{ .Repo }} is:open" {
+		t.Errorf("Expected the template to be left untouched, got %q", got)
+	}
+}
+
+func TestExpandTime(t *testing.T) {
+	tmpl := config.RequestConfig{ApiPath: "/search/issues?q=repo:{{ .Repo }}+created:>{{ daysAgo 7 }}"}
+	req, err := expandTemplate(tmpl, map[string]string{"Repo": "acme/app"})
+	if err != nil {
+		t.Fatalf("Failed to expand template: %v", err)
+	}
+	if want := "/search/issues?q=repo:acme/app+created:>{{ daysAgo 7 }}"; req.ApiPath != want {
+		t.Errorf("Expected the time function to be kept, got %s", req.ApiPath)
+	}
+
+	req, err = expandTime(req, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to expand time: %v", err)
+	}
+	if want := "/search/issues?q=repo:acme/app+created:>2024-03-01"; req.ApiPath != want {
+		t.Errorf("Expected %s, got %s", want, req.ApiPath)
+	}
+}
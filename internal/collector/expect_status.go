@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// expectedStatusError reports a non-2xx status listed in expect_status.
+// It is not a failure: the metrics of the request take the value mapped by
+// status_values, if any.
+type expectedStatusError struct {
+	code int
+}
+
+func (e *expectedStatusError) Error() string {
+	return fmt.Sprintf("expected status code %d", e.code)
+}
+
+// expectedStatus reports whether code is accepted for req: any 2xx by
+// default, or exactly the codes of expect_status.
+func expectedStatus(req config.RequestConfig, code int) bool {
+	if len(req.ExpectStatus) == 0 {
+		return code >= 200 && code < 300
+	}
+	return slices.Contains(req.ExpectStatus, code)
+}
+
+// statusSamples gives every metric of j the value status_values maps code
+// to. Without a mapping the request produces no series.
+func (m *Manager) statusSamples(j *job, code int, fetchedAt time.Time) []sample {
+	value, ok := j.req.StatusValues[code]
+	if !ok {
+		return nil
+	}
+
+	var samples []sample
+	for _, metric := range j.req.Metrics {
+		info, exists := m.metrics[metric.Name]
+		if !exists || metric.MetricType == config.MetricHistogram {
+			continue
+		}
+		labelValues := make([]string, len(info.LabelKeys))
+		for i, key := range info.LabelKeys {
+			if key == "api_path" {
+				labelValues[i] = j.req.ApiPath
+				continue
+			}
+			labelValues[i] = j.labels[key] // path labels have nothing to read
+		}
+		if !info.filter.keep(info.LabelKeys, labelValues) {
+			continue
+		}
+		samples = append(samples, sample{
+			info:        info,
+			labelValues: labelValues,
+			value:       value,
+			fetchedAt:   fetchedAt,
+		})
+	}
+	return samples
+}
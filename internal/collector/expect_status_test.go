@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollect_ExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/CODEOWNERS") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"size": 120}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	request := func(apiPath string) config.RequestConfig {
+		return config.RequestConfig{
+			ApiPath:      apiPath,
+			ExpectStatus: []int{200, 404},
+			StatusValues: map[int]float64{404: 0},
+			Metrics: []config.MetricConfig{
+				{Name: "github_file_exists", Expr: "1", Help: "Whether the file exists"},
+			},
+		}
+	}
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			request("/repos/acme/app/contents/LICENSE"),
+			request("/repos/acme/app/contents/CODEOWNERS"),
+		},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_file_exists Whether the file exists
+# TYPE github_file_exists gauge
+github_file_exists{api_path="/repos/acme/app/contents/CODEOWNERS"} 0
+github_file_exists{api_path="/repos/acme/app/contents/LICENSE"} 1
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_file_exists"); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.CollectAndCount(m.SelfMetrics(), "github_exporter_request_errors_total"); got != 0 {
+		t.Errorf("Expected no request errors, got %d series", got)
+	}
+}
+
+func TestExpectedStatus(t *testing.T) {
+	tests := []struct {
+		expect   []int
+		code     int
+		expected bool
+	}{
+		{nil, 200, true},
+		{nil, 204, true},
+		{nil, 404, false},
+		{[]int{200, 404}, 404, true},
+		{[]int{200, 404}, 403, false},
+		{[]int{404}, 200, false},
+	}
+	for _, tt := range tests {
+		if got := expectedStatus(config.RequestConfig{ExpectStatus: tt.expect}, tt.code); got != tt.expected {
+			t.Errorf("expectedStatus(%v, %d): expected %v, got %v", tt.expect, tt.code, tt.expected, got)
+		}
+	}
+}
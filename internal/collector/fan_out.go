@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// fanOut fetches the fan_out endpoint of reqCfg for every value at its path
+// in body and returns the array of the follow-up responses.
+func (m *Manager) fanOut(ctx context.Context, reqCfg config.RequestConfig, body []byte) ([]byte, error) {
+	f := reqCfg.FanOut
+	values := gjson.GetBytes(body, f.Path).Array()
+	if len(values) > f.MaxItems {
+		logger.Warn("Reached fan_out max_items, results are truncated", append(logArgs(reqCfg), "max_items", f.MaxItems)...)
+		values = values[:f.MaxItems]
+	}
+
+	responses := make([]json.RawMessage, 0, len(values))
+	for _, v := range values {
+		child := config.RequestConfig{
+			ApiPath:         f.ApiPath,
+			BaseURL:         reqCfg.BaseURL,
+			Token:           reqCfg.Token,
+			Timeout:         reqCfg.Timeout,
+			AcceptedRetries: reqCfg.AcceptedRetries,
+			AcceptedDelay:   reqCfg.AcceptedDelay,
+		}
+		child, err := expandTemplate(child, map[string]string{"Item": v.String()})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := m.fetch(ctx, child)
+		if err != nil {
+			return nil, fmt.Errorf("fan_out of %s: %w", reqCfg.ApiPath, err)
+		}
+		if !gjson.ValidBytes(resp) {
+			resp = []byte("null")
+		}
+		responses = append(responses, json.RawMessage(resp))
+	}
+	return json.Marshal(responses)
+}
@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestFetchBody_FanOut(t *testing.T) {
+	responses := map[string]string{
+		"/repos/acme/app/actions/runs":                        `{"workflow_runs": [{"id": 11}, {"id": 12}, {"id": 13}]}`,
+		"/repos/acme/app/actions/runs/11/pending_deployments": `[{"environment": {"name": "production"}}]`,
+		"/repos/acme/app/actions/runs/12/pending_deployments": `[]`,
+		"/repos/acme/app/actions/runs/13/pending_deployments": `[{"environment": {"name": "production"}}, {"environment": {"name": "staging"}}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			t.Errorf("Expected the request token on %s, got '%s'", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app/actions/runs?status=waiting",
+			Token:   "request-token",
+			FanOut: &config.FanOutConfig{
+				Path:     "workflow_runs.#.id",
+				ApiPath:  "/repos/acme/app/actions/runs/{{ .Item }}/pending_deployments",
+				MaxItems: 20,
+			},
+			Metrics: []config.MetricConfig{
+				{
+					Name:      "github_actions_runs_waiting_approval",
+					Path:      "#(#>0)#",
+					Help:      "Runs waiting for an approval",
+					Aggregate: config.AggregateCount,
+				},
+				{
+					Name:       "github_deployments_pending_approval",
+					Path:       "@flatten",
+					Help:       "Pending deployments per environment",
+					GroupBy:    "environment.name",
+					GroupLabel: "environment",
+					Aggregate:  config.AggregateCount,
+				},
+			},
+		}},
+	}
+	m := NewManager(cfg)
+
+	samples, err := m.scrape(context.Background(), m.jobs[0])
+	if err != nil {
+		t.Fatalf("Failed to scrape: %v", err)
+	}
+
+	want := map[string]float64{
+		"github_actions_runs_waiting_approval":           2,
+		"github_deployments_pending_approval/production": 2,
+		"github_deployments_pending_approval/staging":    1,
+	}
+	if len(samples) != len(want) {
+		t.Fatalf("Expected %d samples, got %d", len(want), len(samples))
+	}
+	for _, s := range samples {
+		key := s.info.Config.Name
+		if env := labelValuesFor(s, []string{"environment"}); env[0] != "" {
+			key += "/" + env[0]
+		}
+		if s.value != want[key] {
+			t.Errorf("Expected %v for %s, got %v", want[key], key, s.value)
+		}
+	}
+}
+
+func TestFetchBody_FanOutMaxItems(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body := `[]`
+		if r.URL.Path == "/items" {
+			body = `[1, 2, 3, 4]`
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	body, err := m.fetchBody(context.Background(), config.RequestConfig{
+		ApiPath: "/items",
+		FanOut:  &config.FanOutConfig{Path: "@this", ApiPath: "/items/{{ .Item }}", MaxItems: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if string(body) != `[[],[]]` {
+		t.Errorf("Expected the two follow-up responses, got %s", body)
+	}
+}
+
+func TestFetchBody_FanOutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `[1]`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	_, err := m.fetchBody(context.Background(), config.RequestConfig{
+		ApiPath: "/items",
+		FanOut:  &config.FanOutConfig{Path: "@this", ApiPath: "/items/{{ .Item }}", MaxItems: 20},
+	})
+	if err == nil {
+		t.Error("Expected a failed follow-up request to fail the fetch")
+	}
+}
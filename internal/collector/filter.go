@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"regexp"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// labelFilter drops samples based on their label values. A label missing
+// from the sample is matched as an empty value.
+type labelFilter struct {
+	allow map[string]*regexp.Regexp
+	deny  map[string]*regexp.Regexp
+}
+
+// newLabelFilter compiles the label_allow and label_deny patterns of a
+// metric. It returns nil when the metric has none. Patterns are validated
+// when the config is loaded, invalid ones are ignored here.
+func newLabelFilter(metric config.MetricConfig) *labelFilter {
+	if len(metric.LabelAllow) == 0 && len(metric.LabelDeny) == 0 {
+		return nil
+	}
+	return &labelFilter{
+		allow: compilePatterns(metric.LabelAllow),
+		deny:  compilePatterns(metric.LabelDeny),
+	}
+}
+
+func compilePatterns(patterns map[string]string) map[string]*regexp.Regexp {
+	compiled := make(map[string]*regexp.Regexp, len(patterns))
+	for label, expr := range patterns {
+		if re, err := regexp.Compile(expr); err == nil {
+			compiled[label] = re
+		}
+	}
+	return compiled
+}
+
+// keep reports whether a sample with the given label values passes every
+// allow pattern and no deny pattern.
+func (f *labelFilter) keep(labelKeys, labelValues []string) bool {
+	if f == nil {
+		return true
+	}
+	value := func(label string) string {
+		for i, k := range labelKeys {
+			if k == label {
+				return labelValues[i]
+			}
+		}
+		return ""
+	}
+	for label, re := range f.allow {
+		if !re.MatchString(value(label)) {
+			return false
+		}
+	}
+	for label, re := range f.deny {
+		if re.MatchString(value(label)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestLabelFilter_Keep(t *testing.T) {
+	f := newLabelFilter(config.MetricConfig{
+		LabelAllow: map[string]string{"repo": "^acme/service-"},
+		LabelDeny:  map[string]string{"repo": "-legacy$"},
+	})
+	keys := []string{"api_path", "repo"}
+
+	tests := []struct {
+		repo string
+		want bool
+	}{
+		{"acme/service-api", true},
+		{"acme/service-api-legacy", false},
+		{"acme/website", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := f.keep(keys, []string{"/repos", tt.repo}); got != tt.want {
+			t.Errorf("keep(%q): expected %v, got %v", tt.repo, tt.want, got)
+		}
+	}
+}
+
+func TestLabelFilter_NilKeepsEverything(t *testing.T) {
+	f := newLabelFilter(config.MetricConfig{})
+	if f != nil {
+		t.Fatal("Expected no filter for a metric without patterns")
+	}
+	if !f.keep([]string{"repo"}, []string{"anything"}) {
+		t.Error("Expected a nil filter to keep the sample")
+	}
+}
+
+func TestParseSamples_DropsFilteredSamples(t *testing.T) {
+	cfg := &config.Config{
+		Discovery: []config.DiscoveryConfig{{
+			Org: "acme",
+			Requests: []config.RequestConfig{{
+				ApiPath: "/repos/{{ .Repo }}",
+				Metrics: []config.MetricConfig{{
+					Name:      "github_stars",
+					Path:      "stargazers_count",
+					Help:      "Stars",
+					LabelDeny: map[string]string{"repo": "^acme/sandbox"},
+				}},
+			}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	kept := m.parseSamples(&job{req: cfg.Discovery[0].Requests[0], labels: map[string]string{"repo": "acme/api"}}, `{"stargazers_count": 3}`)
+	if len(kept) != 1 {
+		t.Errorf("Expected 1 sample for acme/api, got %d", len(kept))
+	}
+	dropped := m.parseSamples(&job{req: cfg.Discovery[0].Requests[0], labels: map[string]string{"repo": "acme/sandbox-1"}}, `{"stargazers_count": 3}`)
+	if len(dropped) != 0 {
+		t.Errorf("Expected no sample for acme/sandbox-1, got %d", len(dropped))
+	}
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// decodeBody converts a response in the response_format of req to JSON, so
+// metric paths work the same whatever GitHub, or the system behind GHES,
+// answered with. Empty bodies are left alone for on_empty.
+func decodeBody(req config.RequestConfig, body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body, nil
+	}
+	var (
+		v   any
+		err error
+	)
+	switch req.ResponseFormat {
+	case config.FormatText:
+		v = string(body)
+	case config.FormatCSV:
+		v, err = csvToJSON(body)
+	case config.FormatXML:
+		v, err = xmlToJSON(body)
+	default:
+		return body, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s response of %s: %w", req.ResponseFormat, req.ApiPath, err)
+	}
+	return json.Marshal(v)
+}
+
+// csvToJSON turns CSV rows into objects keyed by the header row, so
+// "#.duration" reads the duration column. Values stay strings; numeric ones
+// are still read as numbers by metric paths.
+func csvToJSON(body []byte) ([]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := []map[string]string{}
+	if len(records) == 0 {
+		return rows, nil
+	}
+	header := records[0]
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, field := range record {
+			if i < len(header) {
+				row[header[i]] = field
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+type xmlElement struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlElement
+	text     strings.Builder
+}
+
+// xmlToJSON turns an XML document into nested objects: the root element is
+// the only key of the result, attributes become "-name" keys, repeated
+// child elements become arrays, and elements with nothing but text become
+// strings (their text is "_text" otherwise).
+func xmlToJSON(body []byte) (map[string]any, error) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	var (
+		stack []*xmlElement
+		root  *xmlElement
+	)
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &xmlElement{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			} else if root == nil {
+				root = el
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("no root element")
+	}
+	return map[string]any{root.name: root.value()}, nil
+}
+
+func (el *xmlElement) value() any {
+	text := strings.TrimSpace(el.text.String())
+	if len(el.attrs) == 0 && len(el.children) == 0 {
+		return text
+	}
+
+	obj := make(map[string]any)
+	for _, a := range el.attrs {
+		obj["-"+a.Name.Local] = a.Value
+	}
+	for _, child := range el.children {
+		v := child.value()
+		switch existing := obj[child.name].(type) {
+		case nil:
+			obj[child.name] = v
+		case []any:
+			obj[child.name] = append(existing, v)
+		default:
+			obj[child.name] = []any{existing, v}
+		}
+	}
+	if text != "" {
+		obj["_text"] = text
+	}
+	return obj
+}
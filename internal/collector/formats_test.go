@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestDecodeBody_CSV(t *testing.T) {
+	body := "name,duration,status\nbuild,120,success\ntest,300,failure\nlint,15\n"
+	got, err := decodeBody(config.RequestConfig{ResponseFormat: config.FormatCSV}, []byte(body))
+	if err != nil {
+		t.Fatalf("decodeBody failed: %v", err)
+	}
+	expected := `[{"duration":"120","name":"build","status":"success"},{"duration":"300","name":"test","status":"failure"},{"duration":"15","name":"lint"}]`
+	if string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestDecodeBody_XML(t *testing.T) {
+	body := `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Releases</title>
+  <entry id="1"><title>v1.1.0</title></entry>
+  <entry id="2"><title>v1.0.0</title></entry>
+  <count unit="releases">2</count>
+</feed>`
+	got, err := decodeBody(config.RequestConfig{ResponseFormat: config.FormatXML}, []byte(body))
+	if err != nil {
+		t.Fatalf("decodeBody failed: %v", err)
+	}
+	expected := `{"feed":{"-xmlns":"http://www.w3.org/2005/Atom","count":{"-unit":"releases","_text":"2"},"entry":[{"-id":"1","title":"v1.1.0"},{"-id":"2","title":"v1.0.0"}],"title":"Releases"}}`
+	if string(got) != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+func TestDecodeBody_Invalid(t *testing.T) {
+	if _, err := decodeBody(config.RequestConfig{ResponseFormat: config.FormatXML}, []byte("<feed><entry></feed>")); err == nil {
+		t.Error("Expected error for malformed XML")
+	}
+	if _, err := decodeBody(config.RequestConfig{ResponseFormat: config.FormatCSV}, []byte("a,\"b\nc")); err == nil {
+		t.Error("Expected error for malformed CSV")
+	}
+}
+
+func TestCollect_CSVResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, "job,duration\nbuild,120\ntest,300\n"); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath:        "/reports/durations.csv",
+			ResponseFormat: config.FormatCSV,
+			Metrics: []config.MetricConfig{
+				{Name: "github_job_duration_seconds", Path: "#.duration", Aggregate: config.AggregateSum, Help: "Total job duration"},
+			},
+		}},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_job_duration_seconds Total job duration
+# TYPE github_job_duration_seconds gauge
+github_job_duration_seconds{api_path="/reports/durations.csv"} 420
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_job_duration_seconds"); err != nil {
+		t.Error(err)
+	}
+}
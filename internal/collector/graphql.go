@@ -0,0 +1,124 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// fetchGraphQLPages follows pageInfo.endCursor until hasNextPage is false and
+// returns the first page with its nodes array replaced by the nodes of every
+// page, so metric paths are evaluated across the full result set.
+func (m *Manager) fetchGraphQLPages(ctx context.Context, reqCfg config.RequestConfig) ([]byte, error) {
+	p := reqCfg.GraphQLPaginate
+
+	var payload map[string]any
+	if err := json.Unmarshal([]byte(reqCfg.Body), &payload); err != nil {
+		return nil, fmt.Errorf("graphql_paginate requires a JSON body: %w", err)
+	}
+	vars, _ := payload["variables"].(map[string]any)
+	if vars == nil {
+		vars = make(map[string]any)
+		payload["variables"] = vars
+	}
+
+	var (
+		first  []byte
+		nodes  []json.RawMessage
+		cursor any // null on the first page
+	)
+	for page := 0; ; page++ {
+		if p.MaxPages > 0 && page >= p.MaxPages {
+			logger.Warn("Reached max_pages, results are truncated", append(logArgs(reqCfg), "max_pages", p.MaxPages)...)
+			break
+		}
+
+		vars[p.CursorVariable] = cursor
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		pageReq := reqCfg
+		pageReq.Body = string(body)
+
+		resp, err := m.fetch(ctx, pageReq)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = resp
+		}
+		for _, node := range gjson.GetBytes(resp, p.NodesPath).Array() {
+			nodes = append(nodes, json.RawMessage(node.Raw))
+		}
+
+		pageInfo := gjson.GetBytes(resp, p.PageInfoPath)
+		endCursor := pageInfo.Get("endCursor").String()
+		if !pageInfo.Get("hasNextPage").Bool() || endCursor == "" {
+			break
+		}
+		cursor = endCursor
+	}
+
+	if nodes == nil {
+		nodes = []json.RawMessage{}
+	}
+	return setJSONPath(first, p.NodesPath, nodes)
+}
+
+// isGraphQL reports whether reqCfg queries the GraphQL API.
+func isGraphQL(reqCfg config.RequestConfig) bool {
+	path, _, _ := strings.Cut(reqCfg.ApiPath, "?")
+	return reqCfg.GraphQLPaginate != nil || strings.HasSuffix(strings.TrimRight(path, "/"), "/graphql")
+}
+
+// graphQLError turns the "errors" array of a GraphQL response, which GitHub
+// sends with a 200 status, into an error. Even when part of the data is
+// present, the response is rejected rather than exporting zeros for the
+// fields that failed.
+func graphQLError(body []byte) error {
+	errs := gjson.GetBytes(body, "errors")
+	if !errs.IsArray() || len(errs.Array()) == 0 {
+		return nil
+	}
+	var messages []string
+	for _, e := range errs.Array() {
+		msg := e.Get("message").String()
+		if path := e.Get("path"); path.IsArray() {
+			var parts []string
+			for _, p := range path.Array() {
+				parts = append(parts, p.String())
+			}
+			msg = strings.Join(parts, ".") + ": " + msg
+		}
+		messages = append(messages, msg)
+	}
+	return fmt.Errorf("graphql errors: %s", strings.Join(messages, "; "))
+}
+
+// setJSONPath replaces the value at a plain dotted path in doc.
+func setJSONPath(doc []byte, path string, value any) ([]byte, error) {
+	var root any
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+
+	keys := strings.Split(path, ".")
+	node := root
+	for i, key := range keys {
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("path %q does not resolve to an object at %q", path, key)
+		}
+		if i == len(keys)-1 {
+			obj[key] = value
+			break
+		}
+		node = obj[key]
+	}
+	return json.Marshal(root)
+}
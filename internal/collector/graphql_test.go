@@ -0,0 +1,191 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFetchGraphQLPages(t *testing.T) {
+	pages := map[string]string{
+		"": `{"data": {"viewer": {"repositories": {
+			"pageInfo": {"hasNextPage": true, "endCursor": "c1"},
+			"nodes": [{"stars": 1}, {"stars": 2}]}}}}`,
+		"c1": `{"data": {"viewer": {"repositories": {
+			"pageInfo": {"hasNextPage": false, "endCursor": "c2"},
+			"nodes": [{"stars": 3}]}}}}`,
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var payload struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode body: %v", err)
+			return
+		}
+		if payload.Variables["login"] != "octocat" {
+			t.Errorf("Expected existing variables to be kept, got %v", payload.Variables)
+		}
+		cursor, _ := payload.Variables["after"].(string)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, pages[cursor]); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/graphql",
+				Method:  "POST",
+				Body:    `{"query": "query($after: String) { viewer { repositories(first: 2, after: $after) { nodes { stars } } } }", "variables": {"login": "octocat"}}`,
+				GraphQLPaginate: &config.GraphQLPaginateConfig{
+					CursorVariable: "after",
+					PageInfoPath:   "data.viewer.repositories.pageInfo",
+					NodesPath:      "data.viewer.repositories.nodes",
+				},
+				Metrics: []config.MetricConfig{
+					{
+						Name:      "github_stars_total",
+						Path:      "data.viewer.repositories.nodes.#.stars",
+						Help:      "Total stars",
+						Aggregate: config.AggregateSum,
+					},
+					{
+						Name:      "github_repos_total",
+						Path:      "data.viewer.repositories.nodes",
+						Help:      "Total repositories",
+						Aggregate: config.AggregateCount,
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	samples, err := m.scrape(context.Background(), m.jobs[0])
+	if err != nil {
+		t.Fatalf("Failed to scrape: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	if samples[0].value != 6 {
+		t.Errorf("Expected stars sum 6, got %f", samples[0].value)
+	}
+	if samples[1].value != 3 {
+		t.Errorf("Expected 3 repositories, got %f", samples[1].value)
+	}
+}
+
+func TestFetchGraphQLPages_MaxPages(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"data": {"items": {"pageInfo": {"hasNextPage": true, "endCursor": "next"}, "nodes": [1]}}}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	body, err := m.fetchGraphQLPages(context.Background(), config.RequestConfig{
+		ApiPath: "/graphql",
+		Method:  "POST",
+		Body:    `{"query": "{}"}`,
+		GraphQLPaginate: &config.GraphQLPaginateConfig{
+			CursorVariable: "cursor",
+			PageInfoPath:   "data.items.pageInfo",
+			NodesPath:      "data.items.nodes",
+			MaxPages:       3,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch pages: %v", err)
+	}
+
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+	if got := string(body); got != `{"data":{"items":{"nodes":[1,1,1],"pageInfo":{"endCursor":"next","hasNextPage":true}}}}` {
+		t.Errorf("Unexpected merged body: %s", got)
+	}
+}
+
+func TestFetch_GraphQLErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"data": {"repository": null}, "errors": [
+			{"type": "NOT_FOUND", "path": ["repository"], "message": "Could not resolve to a Repository with the name 'acme/gone'."},
+			{"message": "Something went wrong"}]}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/graphql",
+			Method:  "POST",
+			Body:    `{"query": "{ repository(owner: \"acme\", name: \"gone\") { stargazerCount } }"}`,
+			Metrics: []config.MetricConfig{{Name: "github_stars", Path: "data.repository.stargazerCount", Help: "Stars"}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	_, err := m.fetch(context.Background(), cfg.Requests[0])
+	if err == nil {
+		t.Fatal("Expected the GraphQL errors to fail the fetch")
+	}
+	for _, want := range []string{"repository: Could not resolve", "Something went wrong"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in the error, got %q", want, err)
+		}
+	}
+
+	if got := testutil.CollectAndCount(m, "github_stars"); got != 0 {
+		t.Errorf("Expected no github_stars series, got %d", got)
+	}
+	if got := testutil.ToFloat64(m.self.errors.WithLabelValues("/graphql", "")); got != 1 {
+		t.Errorf("Expected 1 request error, got %f", got)
+	}
+}
+
+func TestIsGraphQL(t *testing.T) {
+	tests := []struct {
+		req      config.RequestConfig
+		expected bool
+	}{
+		{config.RequestConfig{ApiPath: "/graphql"}, true},
+		{config.RequestConfig{ApiPath: "/api/graphql"}, true},
+		{config.RequestConfig{ApiPath: "/repos/acme/graphql-client"}, false},
+		{config.RequestConfig{ApiPath: "/users/octocat"}, false},
+		{config.RequestConfig{ApiPath: "/custom", GraphQLPaginate: &config.GraphQLPaginateConfig{}}, true},
+	}
+	for _, tt := range tests {
+		if got := isGraphQL(tt.req); got != tt.expected {
+			t.Errorf("Expected %v for %s, got %v", tt.expected, tt.req.ApiPath, got)
+		}
+	}
+}
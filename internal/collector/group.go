@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// group is the share of an array belonging to one group_by key.
+type group struct {
+	key    string
+	values []string            // value of every group_by path
+	metric config.MetricConfig // metric evaluated on json
+	json   string
+}
+
+// labels maps the group labels of metric to the values of g.
+func (g group) labels(metric config.MetricConfig) map[string]string {
+	_, labels := metric.GroupKeys()
+	extra := make(map[string]string, len(labels))
+	for i, label := range labels {
+		extra[label] = g.values[i]
+	}
+	return extra
+}
+
+// groupItems splits the array the path of metric iterates over by the
+// values of the group_by paths in each item. Every group comes with a copy
+// of metric whose path applies to the group's items, so aggregates are
+// computed per group. Items keep their order, sort_by included.
+func groupItems(jsonStr string, metric config.MetricConfig) []group {
+	items, tail, ok := arrayItems(jsonStr, metric)
+	if !ok {
+		return nil
+	}
+	items, tail = expandNested(items, tail)
+
+	paths, _ := metric.GroupKeys()
+	byKey := make(map[string][]int)
+	values := make(map[string][]string)
+	for i, item := range items {
+		vals := make([]string, len(paths))
+		for j, p := range paths {
+			vals[j] = item.Get(p).String()
+		}
+		key := strings.Join(vals, "\x00")
+		byKey[key] = append(byKey[key], i)
+		values[key] = vals
+	}
+
+	groups := make([]group, 0, len(byKey))
+	for key, indexes := range byKey {
+		groupMetric := metric
+		groupMetric.Path = tail
+		groupMetric.SortBy = ""
+		groupMetric.Limit = 0
+
+		members := make([]string, len(indexes))
+		for i, idx := range indexes {
+			members[i] = items[idx].Raw
+		}
+		groups = append(groups, group{
+			key:    key,
+			values: values[key],
+			metric: groupMetric,
+			json:   rawArray(members),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].key < groups[j].key })
+	return groups
+}
+
+// expandNested flattens arrays nested in items when tail iterates over
+// them, such as the assets of releases with "#.assets.#.download_count".
+// Each inner item replaces the array in a copy of its parent, so group_by
+// paths reach the fields of both levels ("tag_name", "assets.name").
+func expandNested(items []gjson.Result, tail string) ([]gjson.Result, string) {
+	rest, ok := strings.CutPrefix(tail, "#.")
+	if !ok {
+		return items, tail
+	}
+	inner, after, found := strings.Cut(rest, ".#")
+	if !found || (after != "" && !strings.HasPrefix(after, ".")) {
+		return items, tail
+	}
+
+	var expanded []gjson.Result
+	for _, item := range items {
+		for _, child := range item.Get(inner).Array() {
+			raw, err := setJSONPath([]byte(item.Raw), inner, json.RawMessage(child.Raw))
+			if err != nil {
+				continue
+			}
+			expanded = append(expanded, gjson.ParseBytes(raw))
+		}
+	}
+	return expandNested(expanded, "#."+inner+after)
+}
@@ -0,0 +1,234 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+const eventsJSON = `[
+	{"type": "PushEvent", "payload": {"size": 3}},
+	{"type": "WatchEvent", "payload": {}},
+	{"type": "PushEvent", "payload": {"size": 1}}
+]`
+
+func TestGroupItems(t *testing.T) {
+	groups := groupItems(eventsJSON, config.MetricConfig{Path: "#.payload.size", GroupBy: "type"})
+
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].key != "PushEvent" || groups[1].key != "WatchEvent" {
+		t.Errorf("Unexpected group keys: %s, %s", groups[0].key, groups[1].key)
+	}
+	if groups[0].metric.Path != "#.payload.size" {
+		t.Errorf("Expected the per-group path to be #.payload.size, got %s", groups[0].metric.Path)
+	}
+}
+
+func TestParseSamples_GroupBy(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/test/events",
+			Metrics: []config.MetricConfig{{
+				Name:       "github_events",
+				Path:       "#",
+				Help:       "Events by type",
+				GroupBy:    "type",
+				GroupLabel: "type",
+			}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	samples := m.parseSamples(m.jobs[0], eventsJSON)
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+
+	want := map[string]float64{"PushEvent": 2, "WatchEvent": 1}
+	for _, s := range samples {
+		typ := labelValuesFor(s, []string{"type"})[0]
+		if s.value != want[typ] {
+			t.Errorf("Expected %f events of type %s, got %f", want[typ], typ, s.value)
+		}
+	}
+}
+
+func TestParseSamples_GroupByNestedPath(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app/dependabot/alerts",
+			Metrics: []config.MetricConfig{{
+				Name:       "github_dependabot_alerts_open_by_severity",
+				Path:       "#",
+				Help:       "Open alerts by severity",
+				GroupBy:    "security_advisory.severity",
+				GroupLabel: "severity",
+			}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	alerts := `[
+		{"number": 3, "security_advisory": {"severity": "high"}},
+		{"number": 2, "security_advisory": {"severity": "low"}},
+		{"number": 1, "security_advisory": {"severity": "high"}}
+	]`
+	samples := m.parseSamples(m.jobs[0], alerts)
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+
+	want := map[string]float64{"high": 2, "low": 1}
+	for _, s := range samples {
+		severity := labelValuesFor(s, []string{"severity"})[0]
+		if s.value != want[severity] {
+			t.Errorf("Expected %f alerts of severity %s, got %f", want[severity], severity, s.value)
+		}
+	}
+}
+
+func TestParseSamples_GroupByNestedArrays(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app/releases",
+			Metrics: []config.MetricConfig{{
+				Name:       "github_release_asset_downloads",
+				Path:       "#.assets.#.download_count",
+				Help:       "Downloads per asset",
+				GroupBy:    "tag_name,assets.name",
+				GroupLabel: "tag,asset",
+			}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	releases := `[
+		{"tag_name": "v2.0.0", "assets": [{"name": "app.tar.gz", "download_count": 5}, {"name": "app.zip", "download_count": 2}]},
+		{"tag_name": "v1.0.0", "assets": [{"name": "app.tar.gz", "download_count": 40}]},
+		{"tag_name": "v0.1.0", "assets": []}
+	]`
+	samples := m.parseSamples(m.jobs[0], releases)
+	if len(samples) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(samples))
+	}
+
+	want := map[string]float64{"v2.0.0/app.tar.gz": 5, "v2.0.0/app.zip": 2, "v1.0.0/app.tar.gz": 40}
+	for _, s := range samples {
+		key := strings.Join(labelValuesFor(s, []string{"tag", "asset"}), "/")
+		if s.value != want[key] {
+			t.Errorf("Expected %f downloads of %s, got %f", want[key], key, s.value)
+		}
+	}
+}
+
+func TestParseSamples_GroupByWithExpr(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/graphql",
+			Metrics: []config.MetricConfig{{
+				Name:       "github_sponsors_monthly_income_cents_by_tier",
+				Path:       "nodes.#(tier.isOneTime==false)#",
+				Expr:       "tier.monthlyPriceInCents",
+				Help:       "Income per tier",
+				GroupBy:    "tier.name",
+				GroupLabel: "tier",
+			}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	sponsorships := `{"nodes": [
+		{"tier": {"name": "$5 a month", "isOneTime": false, "monthlyPriceInCents": 500}},
+		{"tier": {"name": "$5 a month", "isOneTime": false, "monthlyPriceInCents": 500}},
+		{"tier": {"name": "$25 a month", "isOneTime": false, "monthlyPriceInCents": 2500}},
+		{"tier": {"name": "$100 one time", "isOneTime": true, "monthlyPriceInCents": 10000}}
+	]}`
+	samples := m.parseSamples(m.jobs[0], sponsorships)
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+
+	want := map[string]float64{"$5 a month": 1000, "$25 a month": 2500}
+	for _, s := range samples {
+		tier := labelValuesFor(s, []string{"tier"})[0]
+		if s.value != want[tier] {
+			t.Errorf("Expected %f cents for %s, got %f", want[tier], tier, s.value)
+		}
+	}
+}
+
+const workflowRunsJSON = `{"workflow_runs": [
+	{"name": "CI", "head_branch": "main", "conclusion": "failure", "created_at": "2024-05-03T10:00:00Z", "run_started_at": "2024-05-03T10:00:00Z", "updated_at": "2024-05-03T10:05:00Z"},
+	{"name": "CI", "head_branch": "main", "conclusion": "success", "created_at": "2024-05-02T10:00:00Z", "run_started_at": "2024-05-02T10:00:00Z", "updated_at": "2024-05-02T10:03:00Z"},
+	{"name": "CI", "head_branch": "dev", "conclusion": "success", "created_at": "2024-05-01T10:00:00Z", "run_started_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:01:00Z"},
+	{"name": "Release", "head_branch": "main", "conclusion": "success", "created_at": "2024-05-04T10:00:00Z", "run_started_at": "2024-05-04T10:00:00Z"}
+]}`
+
+func TestParseSamples_GroupByMultiplePaths(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app/actions/runs",
+			Metrics: []config.MetricConfig{
+				{
+					Name:       "github_workflow_runs",
+					Path:       "workflow_runs.#",
+					Help:       "Runs",
+					GroupBy:    "name,head_branch,conclusion",
+					GroupLabel: "workflow,branch,conclusion",
+				},
+				{
+					Name:       "github_workflow_last_conclusion",
+					Path:       "workflow_runs.#.conclusion",
+					Help:       "Latest conclusion",
+					SortBy:     "-created_at",
+					GroupBy:    "name,head_branch",
+					GroupLabel: "workflow,branch",
+					Aggregate:  config.AggregateFirst,
+					ValueMap:   map[string]float64{"success": 0, "failure": 1},
+				},
+				{
+					Name:       "github_workflow_run_duration_seconds",
+					Path:       "workflow_runs",
+					Expr:       "parse_time(updated_at) - parse_time(run_started_at)",
+					Help:       "Average duration",
+					GroupBy:    "name,head_branch",
+					GroupLabel: "workflow,branch",
+					Aggregate:  config.AggregateAvg,
+				},
+			},
+		}},
+	}
+	m := NewManager(cfg)
+
+	want := map[string]float64{
+		"github_workflow_runs/CI/main/failure":               1,
+		"github_workflow_runs/CI/main/success":               1,
+		"github_workflow_runs/CI/dev/success":                1,
+		"github_workflow_runs/Release/main/success":          1,
+		"github_workflow_last_conclusion/CI/main/":           1,
+		"github_workflow_last_conclusion/CI/dev/":            0,
+		"github_workflow_last_conclusion/Release/main/":      0,
+		"github_workflow_run_duration_seconds/CI/main/":      240,
+		"github_workflow_run_duration_seconds/CI/dev/":       60,
+		"github_workflow_run_duration_seconds/Release/main/": 0, // not finished, no item left
+	}
+	samples := m.parseSamples(m.jobs[0], workflowRunsJSON)
+	if len(samples) != len(want) {
+		t.Errorf("Expected %d samples, got %d", len(want), len(samples))
+	}
+	for _, s := range samples {
+		labels := labelValuesFor(s, []string{"workflow", "branch", "conclusion"})
+		key := s.info.Config.Name + "/" + labels[0] + "/" + labels[1] + "/" + labels[2]
+		expected, ok := want[key]
+		if !ok {
+			t.Errorf("Unexpected sample %s", key)
+			continue
+		}
+		if s.value != expected {
+			t.Errorf("Expected %f for %s, got %f", expected, key, s.value)
+		}
+	}
+}
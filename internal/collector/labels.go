@@ -0,0 +1,18 @@
+package collector
+
+import (
+	"html"
+	"strings"
+)
+
+// labelValue makes a value extracted from a response safe to expose.
+// Prometheus rejects label values that are not valid UTF-8, which would
+// drop the whole series, so invalid bytes are replaced with U+FFFD. With
+// unescape, HTML entities such as &amp; found in titles are decoded.
+func labelValue(v string, unescape bool) string {
+	v = strings.ToValidUTF8(v, "�")
+	if unescape {
+		v = html.UnescapeString(v)
+	}
+	return v
+}
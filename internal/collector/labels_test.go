@@ -0,0 +1,71 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLabelValue(t *testing.T) {
+	tests := []struct {
+		in       string
+		unescape bool
+		expected string
+	}{
+		{"héllo 🚀", false, "héllo 🚀"},
+		{"bad \xff\xfe bytes", false, "bad � bytes"},
+		{"Fix &lt;input&gt; &amp; tests", false, "Fix &lt;input&gt; &amp; tests"},
+		{"Fix &lt;input&gt; &amp; tests", true, "Fix <input> & tests"},
+		{"&#39;quoted&#39;", true, "'quoted'"},
+	}
+	for _, tt := range tests {
+		got := labelValue(tt.in, tt.unescape)
+		if got != tt.expected {
+			t.Errorf("labelValue(%q, %v): expected %q, got %q", tt.in, tt.unescape, tt.expected, got)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("labelValue(%q, %v) returned invalid UTF-8", tt.in, tt.unescape)
+		}
+	}
+}
+
+func TestCollect_InvalidUTF8Label(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, "{\"comments\": 3, \"title\": \"Caf\xe9 &amp; tea\"}"); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app/issues/1",
+			Metrics: []config.MetricConfig{{
+				Name:         "github_issue_comments",
+				Path:         "comments",
+				Help:         "Comments",
+				Labels:       map[string]string{"title": "title"},
+				UnescapeHTML: true,
+			}},
+		}},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_issue_comments Comments
+# TYPE github_issue_comments gauge
+github_issue_comments{api_path="/repos/acme/app/issues/1",title="Caf� & tea"} 3
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_issue_comments"); err != nil {
+		t.Error(err)
+	}
+}
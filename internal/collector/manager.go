@@ -1,15 +1,28 @@
 package collector
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/fnv"
 	"io"
-	"log/slog"
+	"maps"
 	"net/http"
-	"sort"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/expr"
+	"github.com/eleboucher/github-exporter/internal/logging"
+	"github.com/eleboucher/github-exporter/internal/schedule"
+	"github.com/eleboucher/github-exporter/internal/semver"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tidwall/gjson"
 )
@@ -18,88 +31,701 @@ type MetricInfo struct {
 	Desc      *prometheus.Desc
 	LabelKeys []string
 	Config    config.MetricConfig
+	filter    *labelFilter
+	freshness *prometheus.Desc // set when the request asks for the fetch time
+	expr      *expr.Expr       // replaces the path when the metric has an expr
+	track     *tracked         // set when the metric has track
 }
 
 type Manager struct {
-	cfg     *config.Config
-	client  *http.Client
-	metrics map[string]*MetricInfo
-	token   string
+	cfg       *config.Config
+	client    *http.Client
+	metrics   map[string]*MetricInfo
+	token     string
+	tokenFile *tokenFile // set for github_token_file, replaces token
+	semaphore chan struct{}
+	jobs      []*job
+	computed  []*computedMetric
+	counters  *counters
+	windows   *windows
+	self      *selfMetrics
+	inflight  *fetchGroup // merges identical fetches running at the same time
+	search    *searchLimiter
+	standby   atomic.Bool // set on HA standbys, which serve cached samples without fetching
+
+	activeMu   sync.Mutex
+	active     context.Context // cancelled when the Manager goes on standby
+	deactivate context.CancelFunc
+
+	mu            sync.RWMutex
+	discovered    map[int][]*job // indexed like cfg.Discovery
+	serverVersion string
+}
+
+// job is a request the Manager is responsible for, either configured
+// directly or generated by discovery.
+type job struct {
+	req      config.RequestConfig
+	labels   map[string]string // constant label values, e.g. the discovered repo
+	schedule schedule.Schedule // nil means the request is fetched on Collect
+	staleTTL time.Duration     // how long samples outlive failed fetches
+
+	// Guarded by Manager.mu.
+	samples     []sample  // last successful result
+	lastSuccess time.Time // time of the fetch samples come from
+	stale       bool      // the fetches since lastSuccess failed
+}
+
+// sample is a parsed value waiting to be exposed as a const metric.
+type sample struct {
+	info        *MetricInfo
+	labelValues []string
+	value       float64
+	histogram   *histogramSample // set for histogram metrics instead of value
+	fetchedAt   time.Time
+	timestamp   time.Time // set when the metric has a timestamp path
 }
 
 func NewManager(cfg *config.Config) *Manager {
-	// Create transport that disables caching
+	// Create transport that disables caching. Responses are requested
+	// gzip-compressed and decoded transparently, which matters for large
+	// lists since connections are not reused.
 	transport := &http.Transport{
-		DisableKeepAlives: true,
+		DisableKeepAlives:  true,
+		DisableCompression: cfg.DisableCompression,
+		MaxConnsPerHost:    cfg.MaxPerHost,
+		Proxy:              proxyFunc(cfg.ProxyURL),
+	}
+	logging.AddSecrets(cfg.Secrets()...)
+	tlsCfg, err := tlsConfig(cfg)
+	if err != nil {
+		logger.Error("Failed to load the TLS settings, using the system defaults", "ca_bundle", cfg.CABundle, "client_cert", cfg.ClientCert, "err", err)
+	}
+	if cfg.InsecureSkipVerify {
+		logger.Warn("TLS certificate verification is disabled for API requests")
+	}
+	transport.TLSClientConfig = tlsCfg
+	concurrency := cfg.MaxConcurrent
+	if concurrency <= 0 {
+		concurrency = config.DefaultMaxConcurrent
+	}
+	searchRate := cfg.SearchRateLimit
+	if searchRate <= 0 {
+		searchRate = config.DefaultSearchRateLimit
 	}
 
 	m := &Manager{
-		cfg:     cfg,
-		client:  &http.Client{
-			Timeout:   10 * time.Second,
-			Transport: transport,
+		cfg: cfg,
+		client: &http.Client{
+			Transport: transport, // deadlines are set per request, see requestTimeout
 		},
-		metrics: make(map[string]*MetricInfo),
-		token:   cfg.Token,
+		metrics:    make(map[string]*MetricInfo),
+		token:      cfg.Token,
+		semaphore:  make(chan struct{}, concurrency),
+		counters:   newCounters(),
+		windows:    newWindows(),
+		self:       newSelfMetrics(),
+		discovered: make(map[int][]*job),
+		inflight:   newFetchGroup(false),
+		search:     newSearchLimiter(searchRate),
+	}
+	m.active, m.deactivate = context.WithCancel(context.Background())
+	if cfg.StateFile != "" {
+		if m.counters, err = loadCounters(cfg.StateFile); err != nil {
+			logger.Error("Failed to load the state file, counters and deltas start over", "state_file", cfg.StateFile, "err", err)
+		}
+	}
+	if cfg.TokenFile != "" {
+		m.tokenFile = newTokenFile(cfg.TokenFile)
+	}
+	m.self.setConfigHash(cfg.Hash)
+	if cfg.Public {
+		warnAuthRequired(cfg)
 	}
 	m.initDescriptors()
+	m.initComputed()
+	for _, req := range cfg.Requests {
+		m.jobs = append(m.jobs, newJob(req, req.TargetLabels))
+	}
 	return m
 }
 
+// githubToken returns github_token, or the current content of
+// github_token_file.
+func (m *Manager) githubToken() string {
+	if m.tokenFile != nil {
+		return m.tokenFile.get()
+	}
+	return m.token
+}
+
+var (
+	logger          = logging.Logger("collector")
+	httpLogger      = logging.Logger("http")      // GitHub API calls
+	schedulerLogger = logging.Logger("scheduler") // background fetches
+)
+
+// logArgs identifies a request in log lines by its api_path, and its name
+// when it has one.
+func logArgs(req config.RequestConfig) []any {
+	if req.Name == "" {
+		return []any{"api_path", req.ApiPath}
+	}
+	return []any{"request", req.Name, "api_path", req.ApiPath}
+}
+
+func newJob(req config.RequestConfig, labels map[string]string) *job {
+	j := &job{req: req, labels: labels}
+	if req.StaleTTL != "" {
+		ttl, err := time.ParseDuration(req.StaleTTL)
+		if err != nil {
+			schedulerLogger.Error("Invalid stale_ttl, ignoring it", append(logArgs(req), "err", err)...)
+		}
+		j.staleTTL = ttl
+	}
+	if req.Interval == "" {
+		return j
+	}
+	sched, err := schedule.Parse(req.Interval)
+	if err != nil {
+		schedulerLogger.Error("Invalid interval, fetching on collect instead", append(logArgs(req), "err", err)...)
+		return j
+	}
+	j.schedule = sched
+	return j
+}
+
 func (m *Manager) initDescriptors() {
 	for _, req := range m.cfg.Requests {
-		for _, metric := range req.Metrics {
-			var labelKeys []string
-			labelKeys = append(labelKeys, "api_path")
-			for k := range metric.Labels {
-				labelKeys = append(labelKeys, k)
-			}
-			sort.Strings(labelKeys)
+		m.addDescriptors(req, slices.Collect(maps.Keys(req.TargetLabels)))
+	}
+	for _, d := range m.cfg.Discovery {
+		for _, req := range d.Requests {
+			m.addDescriptors(req, []string{"repo"})
+		}
+	}
+}
+
+// addDescriptors registers the metrics of a request. extraKeys are label
+// names whose values are supplied by the job rather than a GJSON path.
+func (m *Manager) addDescriptors(req config.RequestConfig, extraKeys []string) {
+	for _, metric := range req.Metrics {
+		labelKeys := metric.LabelKeys(extraKeys)
+
+		constLabels := m.constLabels(metric, labelKeys)
+		desc := prometheus.NewDesc(
+			m.cfg.MetricPrefix+exposedName(metric),
+			metric.Help,
+			labelKeys,
+			constLabels,
+		)
 
-			desc := prometheus.NewDesc(
-				metric.Name,
-				metric.Help,
+		info := &MetricInfo{
+			Desc:      desc,
+			LabelKeys: labelKeys,
+			Config:    metric,
+			filter:    newLabelFilter(metric),
+			track:     newTracked(metric, m.cfg.MetricPrefix, labelKeys, constLabels),
+		}
+		if metric.Expr != "" {
+			e, err := expr.Parse(metric.Expr)
+			if err != nil {
+				logger.Error("Invalid expr, metric will not be exported", "name", metric.Name, "err", err)
+				continue
+			}
+			info.expr = e
+		}
+		if req.Freshness {
+			name := m.cfg.MetricPrefix + exposedName(metric)
+			info.freshness = prometheus.NewDesc(
+				name+"_last_fetch_timestamp_seconds",
+				"Unix time of the fetch "+name+" was read from",
 				labelKeys,
-				nil,
+				constLabels,
 			)
+		}
+		m.metrics[metric.Name] = info
+	}
+}
 
-			m.metrics[metric.Name] = &MetricInfo{
-				Desc:      desc,
-				LabelKeys: labelKeys,
-				Config:    metric,
+// constLabels returns the global labels and the const_labels of a metric.
+// Labels the metric already has, e.g. a discovered repo, are left out.
+func (m *Manager) constLabels(metric config.MetricConfig, labelKeys []string) prometheus.Labels {
+	labels := prometheus.Labels{}
+	for _, src := range []map[string]string{m.cfg.Labels, metric.ConstLabels} {
+		for name, value := range src {
+			if !slices.Contains(labelKeys, name) {
+				labels[name] = value
 			}
 		}
 	}
+	return labels
 }
 
 func (m *Manager) Describe(ch chan<- *prometheus.Desc) {
 	for _, info := range m.metrics {
 		ch <- info.Desc
+		if info.freshness != nil {
+			ch <- info.freshness
+		}
+		if t := info.track; t != nil {
+			for _, derived := range []*MetricInfo{t.min, t.max} {
+				if derived != nil {
+					ch <- derived.Desc
+				}
+			}
+		}
 	}
+	for _, cm := range m.computed {
+		ch <- cm.info.Desc
+	}
+	ch <- serverVersionDesc
 }
 
-func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+// Start detects the GitHub server version, then launches the background
+// fetchers for every request that has an interval and the repository
+// discovery loops. They stop when ctx is cancelled.
+func (m *Manager) Start(ctx context.Context) {
+	m.detectServerVersion(ctx)
+
+	for _, j := range m.jobs {
+		if j.schedule == nil {
+			continue
+		}
+		if !m.supported(j.req) {
+			logger.Info("Skipping request, server version too old", append(logArgs(j.req), "min_server_version", j.req.MinServerVersion)...)
+			continue
+		}
+		go m.runScheduled(ctx, j)
+	}
+
+	for i, d := range m.cfg.Discovery {
+		go m.runDiscovery(ctx, i, d)
+	}
+	if m.cfg.StateFile != "" {
+		go m.runStateSaver(ctx)
+	}
+}
+
+// Prime detects the server version, runs discovery and fetches every
+// scheduled request once, so that a single Collect without Start exposes
+// the complete metric set. It is meant for one-shot collection.
+func (m *Manager) Prime() {
+	ctx := context.Background()
+	m.detectServerVersion(ctx)
+
+	for i, d := range m.cfg.Discovery {
+		jobs, err := m.discover(ctx, d)
+		if err != nil {
+			logger.Error("Repository discovery failed", "org", d.Org, "err", err)
+			continue
+		}
+		m.mu.Lock()
+		m.discovered[i] = jobs
+		m.mu.Unlock()
+	}
+
+	m.refreshScheduled(ctx)
+}
+
+// refreshScheduled fetches every scheduled request once and waits for
+// them.
+func (m *Manager) refreshScheduled(ctx context.Context) {
 	var wg sync.WaitGroup
+	for _, j := range m.allJobs() {
+		if j.schedule == nil || !m.supported(j.req) {
+			continue
+		}
+		wg.Add(1)
+		go func(j *job) {
+			defer wg.Done()
+			m.refresh(ctx, j)
+		}(j)
+	}
+	wg.Wait()
+}
 
-	semaphore := make(chan struct{}, 5)
+// SetStandby stops or resumes fetching from GitHub. A standby keeps
+// serving the samples it cached before and interrupts the fetches in
+// flight; a Manager leaving standby refreshes its scheduled requests right
+// away.
+func (m *Manager) SetStandby(standby bool) {
+	m.activeMu.Lock()
+	if standby {
+		m.deactivate()
+	} else if m.active.Err() != nil {
+		m.active, m.deactivate = context.WithCancel(context.Background())
+	}
+	m.activeMu.Unlock()
+
+	if m.standby.Swap(standby) && !standby {
+		go m.refreshScheduled(context.Background())
+	}
+}
+
+// whileActive returns a context derived from ctx that is also cancelled
+// when the Manager goes on standby.
+func (m *Manager) whileActive(ctx context.Context) (context.Context, context.CancelFunc) {
+	m.activeMu.Lock()
+	active := m.active
+	m.activeMu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(active, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (m *Manager) runScheduled(ctx context.Context, j *job) {
+	for {
+		m.refresh(ctx, j)
+
+		if !sleepUntilNext(ctx, j.schedule) {
+			return
+		}
+	}
+}
+
+// sleepUntilNext blocks until the next activation of sched. It returns
+// false when ctx is cancelled or the schedule will never fire again.
+func sleepUntilNext(ctx context.Context, sched schedule.Schedule) bool {
+	next := sched.Next(time.Now())
+	if next.IsZero() {
+		schedulerLogger.Warn("Schedule will never fire again, stopping background fetch")
+		return false
+	}
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// refresh fetches a scheduled job and replaces its cached samples. An
+// interrupted fetch keeps the cached samples.
+func (m *Manager) refresh(ctx context.Context, j *job) {
+	if m.standby.Load() {
+		return
+	}
+	ctx, stop := m.whileActive(ctx)
+	defer stop()
+
+	m.semaphore <- struct{}{}
+	samples, err := m.scrape(ctx, j)
+	<-m.semaphore
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		schedulerLogger.Error("Background fetch failed", append(logArgs(j.req), "err", err)...)
+	}
+	m.record(j, samples, err)
+}
+
+// record stores the outcome of a fetch of j and returns the samples to
+// expose: the new ones or, while the last success is within stale_ttl,
+// the previous ones.
+func (m *Manager) record(j *job, samples []sample, err error) []sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err == nil {
+		samples = m.windows.observe(samples, time.Now())
+		j.samples = samples
+		j.lastSuccess = time.Now()
+		j.stale = false
+		return samples
+	}
+	if j.staleTTL > 0 && !j.lastSuccess.IsZero() && time.Since(j.lastSuccess) <= j.staleTTL {
+		j.stale = true
+		return j.samples
+	}
+	j.samples = nil
+	j.stale = false
+	return nil
+}
+
+// cached returns the samples of a scheduled job, unless they are stale for
+// longer than its stale_ttl.
+func (m *Manager) cached(j *job) []sample {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if j.stale && time.Since(j.lastSuccess) > j.staleTTL {
+		return nil
+	}
+	return j.samples
+}
+
+// allJobs returns the configured jobs followed by the discovered ones.
+func (m *Manager) allJobs() []*job {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jobs := append([]*job(nil), m.jobs...)
+	for i := range m.cfg.Discovery {
+		jobs = append(jobs, m.discovered[i]...)
+	}
+	return jobs
+}
+
+func (m *Manager) Collect(ch chan<- prometheus.Metric) {
+	m.collectJobs(m.allJobs(), ch)
+}
+
+// CollectGroup collects the requests of group only, for /metrics/<group>.
+func (m *Manager) CollectGroup(group string, ch chan<- prometheus.Metric) {
+	var jobs []*job
+	for _, j := range m.allJobs() {
+		if j.req.Group == group {
+			jobs = append(jobs, j)
+		}
+	}
+	m.collectJobs(jobs, ch)
+}
+
+// hasGroup reports whether a request of the configuration is in group.
+func (m *Manager) hasGroup(group string) bool {
+	for _, j := range m.allJobs() {
+		if j.req.Group == group {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) collectJobs(jobs []*job, ch chan<- prometheus.Metric) {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		samples []sample
+	)
+
+	m.collectServerVersion(ch)
+
+	ctx, stop := m.whileActive(context.Background())
+	defer stop()
+	cycle := newFetchGroup(true)
+	for _, j := range jobs {
+		if !m.supported(j.req) {
+			logger.Debug("Skipping request, server version too old", logArgs(j.req)...)
+			continue
+		}
+		if j.schedule != nil || m.standby.Load() {
+			cached := m.cached(j)
+			mu.Lock()
+			samples = append(samples, cached...)
+			mu.Unlock()
+			continue
+		}
 
-	for _, req := range m.cfg.Requests {
 		wg.Add(1)
-		go func(r config.RequestConfig) {
+		go func(j *job) {
 			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			m.semaphore <- struct{}{}
+			defer func() { <-m.semaphore }()
 
-			m.fetchAndCollect(r, ch)
-		}(req)
+			scraped, err := m.scrapeIn(ctx, j, cycle)
+			if err != nil {
+				logger.Error("Fetch failed", append(logArgs(j.req), "err", err)...)
+			}
+			scraped = m.record(j, scraped, err)
+			mu.Lock()
+			samples = append(samples, scraped...)
+			mu.Unlock()
+		}(j)
 	}
 	wg.Wait()
+
+	m.emit(m.selectTopN(samples), ch)
+	m.emit(m.computeSamples(samples), ch)
 }
 
-func (m *Manager) fetchAndCollect(reqCfg config.RequestConfig, ch chan<- prometheus.Metric) {
-	path := strings.TrimLeft(reqCfg.ApiPath, "/")
-	url := m.cfg.GithubAPIURL + "/" + path
+func (m *Manager) emit(samples []sample, ch chan<- prometheus.Metric) {
+	for _, s := range samples {
+		var (
+			metric prometheus.Metric
+			err    error
+		)
+		switch {
+		case s.histogram != nil:
+			metric, err = prometheus.NewConstHistogram(
+				s.info.Desc,
+				s.histogram.count,
+				s.histogram.sum,
+				s.histogram.buckets,
+				s.labelValues...,
+			)
+		case s.info.Config.MetricType == config.MetricCounter:
+			metric, err = prometheus.NewConstMetric(
+				s.info.Desc,
+				prometheus.CounterValue,
+				s.value,
+				s.labelValues...,
+			)
+		default:
+			metric, err = prometheus.NewConstMetric(
+				s.info.Desc,
+				prometheus.GaugeValue,
+				s.value,
+				s.labelValues...,
+			)
+		}
+		if err != nil {
+			logger.Error("Failed to create metric", "name", s.info.Config.Name, "err", err)
+			continue
+		}
+		if !s.timestamp.IsZero() {
+			metric = prometheus.NewMetricWithTimestamp(s.timestamp, metric)
+		}
+
+		ch <- metric
+
+		if s.info.freshness != nil && !s.fetchedAt.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				s.info.freshness,
+				prometheus.GaugeValue,
+				float64(s.fetchedAt.Unix()),
+				s.labelValues...,
+			)
+		}
+	}
+}
+
+// scrape fetches a job's request and parses every metric it declares.
+func (m *Manager) scrape(ctx context.Context, j *job) ([]sample, error) {
+	return m.scrapeIn(ctx, j, m.inflight)
+}
 
+// scrapeIn scrapes j, sharing the response with identical requests of
+// group.
+func (m *Manager) scrapeIn(ctx context.Context, j *job, group *fetchGroup) ([]sample, error) {
+	start := time.Now()
+	body, shared, err := group.do(m.fetchKey(j.req), func() ([]byte, error) {
+		body, err := m.fetchBody(ctx, j.req)
+		if err != nil {
+			return nil, err
+		}
+		return decodeBody(j.req, body)
+	})
+	if shared {
+		logger.Debug("Reusing response of an identical request", logArgs(j.req)...)
+	}
+	var expected *expectedStatusError
+	if errors.As(err, &expected) {
+		m.self.observe(j.req, time.Since(start), nil)
+		return m.statusSamples(j, expected.code, start), nil
+	}
+	m.self.observe(j.req, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(body)) == 0 && j.req.OnEmpty != config.EmptyZero {
+		logger.Debug("Empty response, skipping metrics", logArgs(j.req)...)
+		return nil, nil
+	}
+	samples := m.parseSamples(j, string(body))
+	for i := range samples {
+		samples[i].fetchedAt = start
+	}
+	return samples, nil
+}
+
+// fetchBody fetches a request, walking every page when it is paginated and
+// following up on every item with fan_out.
+func (m *Manager) fetchBody(ctx context.Context, req config.RequestConfig) ([]byte, error) {
+	req, err := expandTime(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	switch {
+	case req.GraphQLPaginate != nil:
+		body, err = m.fetchGraphQLPages(ctx, req)
+	case req.Paginate != nil:
+		body, err = m.fetchPages(ctx, req)
+	default:
+		body, err = m.fetch(ctx, req)
+	}
+	if err != nil || req.FanOut == nil {
+		return body, err
+	}
+	return m.fanOut(ctx, req, body)
+}
+
+// response is a successful API response.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (m *Manager) requestURL(reqCfg config.RequestConfig) string {
+	u := reqCfg.ApiPath
+	if !config.IsAbsoluteURL(u) {
+		base := m.cfg.GithubAPIURL
+		if reqCfg.BaseURL != "" {
+			base = reqCfg.BaseURL
+		}
+		u = base + "/" + strings.TrimLeft(reqCfg.ApiPath, "/")
+	}
+	if len(reqCfg.QueryParams) > 0 {
+		u = withQuery(u, reqCfg.QueryParams)
+	}
+	return u
+}
+
+func (m *Manager) fetch(ctx context.Context, reqCfg config.RequestConfig) ([]byte, error) {
+	resp, err := m.fetchURL(ctx, reqCfg, m.requestURL(reqCfg))
+	if err != nil {
+		return nil, err
+	}
+	if isGraphQL(reqCfg) {
+		if err := graphQLError(resp.body); err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", m.requestURL(reqCfg), err)
+		}
+	}
+	return resp.body, nil
+}
+
+// fetchURL sends reqCfg to url, which is usually requestURL(reqCfg) but may
+// also be a pagination link. While GitHub answers 202 Accepted, as the
+// statistics endpoints do while they compute their results, the call is
+// retried up to accepted_retries times, unless ctx is cancelled in between.
+func (m *Manager) fetchURL(ctx context.Context, reqCfg config.RequestConfig, url string) (*response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := m.fetchOnce(ctx, reqCfg, url)
+		if err != nil || resp.status != http.StatusAccepted {
+			return resp, err
+		}
+		if attempt >= reqCfg.AcceptedRetries {
+			return nil, fmt.Errorf("%s still computing after %d attempts (202 Accepted)", url, attempt+1)
+		}
+		delay := acceptedRetryDelay(reqCfg)
+		httpLogger.Debug("GitHub is computing the response, retrying", "url", url, "attempt", attempt+1, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func acceptedRetryDelay(reqCfg config.RequestConfig) time.Duration {
+	d, err := time.ParseDuration(reqCfg.AcceptedDelay)
+	if err != nil {
+		d, _ = time.ParseDuration(config.DefaultAcceptedRetryDelay)
+	}
+	return d
+}
+
+func (m *Manager) fetchOnce(ctx context.Context, reqCfg config.RequestConfig, url string) (*response, error) {
 	method := reqCfg.Method
 	if method == "" {
 		method = "GET"
@@ -108,12 +734,49 @@ func (m *Manager) fetchAndCollect(reqCfg config.RequestConfig, ch chan<- prometh
 	var bodyReader io.Reader
 	if reqCfg.Body != "" {
 		bodyReader = strings.NewReader(reqCfg.Body)
+		if reqCfg.CompressBody {
+			compressed, err := gzipBody(reqCfg.Body)
+			if err != nil {
+				return nil, fmt.Errorf("compressing body for %s: %w", url, err)
+			}
+			bodyReader = compressed
+		}
 	}
 
-	req, err := http.NewRequest(method, url, bodyReader)
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout(reqCfg))
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
 	if err != nil {
-		slog.Error("Error creating request for", "url", url, "err", err)
-		return
+		return nil, fmt.Errorf("creating request for %s: %w", url, err)
+	}
+
+	id := rand.Text()
+	resp, err := m.do(req, reqCfg, id)
+	if err != nil {
+		httpLogger.Debug("API call failed", "url", url, "request_id", id, "err", err)
+		return nil, &requestError{id: id, err: err}
+	}
+	return resp, nil
+}
+
+// requestTimeout is the deadline of every API call of reqCfg, including
+// reading the response body.
+func requestTimeout(reqCfg config.RequestConfig) time.Duration {
+	if reqCfg.Timeout != "" {
+		if d, err := time.ParseDuration(reqCfg.Timeout); err == nil && d > 0 {
+			return d
+		}
+	}
+	return config.DefaultTimeout
+}
+
+// do sends an API call tagged with the correlation ID id.
+func (m *Manager) do(req *http.Request, reqCfg config.RequestConfig, id string) (*response, error) {
+	url := req.URL.String()
+	method := req.Method
+
+	if header := m.cfg.RequestIDHeader; header != "" {
+		req.Header.Set(header, id)
 	}
 
 	req.Header.Set("User-Agent", "eleboucher-github-exporter/1.0")
@@ -121,125 +784,420 @@ func (m *Manager) fetchAndCollect(reqCfg config.RequestConfig, ch chan<- prometh
 	req.Header.Set("Pragma", "no-cache")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	if m.token != "" {
-		req.Header.Add("Authorization", "Bearer "+m.token)
+	// github_token is only sent to github_api_url, requests to other hosts
+	// need their own token
+	var token string
+	if sameOrigin(m.cfg.GithubAPIURL, url) {
+		token = m.githubToken()
+	}
+	if reqCfg.Token != "" {
+		token = reqCfg.Token
+	}
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
 	}
 
 	if method == "POST" {
 		req.Header.Add("Content-Type", "application/json")
 	}
+	if reqCfg.ResponseFormat == config.FormatText {
+		// Makes the contents API return the file itself instead of JSON.
+		req.Header.Set("Accept", "application/vnd.github.raw+json")
+	}
+	if reqCfg.Body != "" && reqCfg.CompressBody {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 
-	resp, err := m.client.Do(req)
+	search := m.isSearch(req.URL)
+	if search {
+		if err := m.search.wait(req.Context()); err != nil {
+			return nil, fmt.Errorf("fetching %s: %w", url, err)
+		}
+	}
+
+	resp, err := m.client.Do(m.self.trace(req))
 	if err != nil {
-		slog.Error("Error fetching", "url", url, "err", err)
-		return
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
-			slog.Error("Error closing response body", "err", err)
+			httpLogger.Error("Error closing response body", "err", err)
 		}
 	}()
+	if search {
+		m.search.update(resp.Header)
+	}
 
 	// Log cache-related headers to debug caching issues
-	slog.Debug("Response headers",
+	httpLogger.Debug("Response headers",
 		"url", url,
+		"request_id", id,
 		"etag", resp.Header.Get("ETag"),
 		"cache-control", resp.Header.Get("Cache-Control"),
 		"age", resp.Header.Get("Age"),
 		"x-github-request-id", resp.Header.Get("X-GitHub-Request-Id"))
 
+	if !expectedStatus(reqCfg, resp.StatusCode) {
+		return nil, m.statusError(resp, url)
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		slog.Error("Non-200 status code from", "url", url, "status_code", resp.StatusCode)
-		return
+		return nil, &expectedStatusError{code: resp.StatusCode}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	limit := m.cfg.MaxResponseBytes
+	if limit <= 0 {
+		limit = config.DefaultMaxResponseBytes
+	}
+	// One byte more than the limit tells a response of exactly the limit
+	// apart from a larger one.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
 	if err != nil {
-		slog.Error("Error reading response body", "url", url, "err", err)
-		return
+		return nil, fmt.Errorf("reading response body from %s: %w", url, err)
+	}
+	if int64(len(body)) > limit {
+		m.self.truncated(reqCfg)
+		return nil, fmt.Errorf("response from %s exceeds max_response_bytes (%d)", url, limit)
 	}
-	jsonStr := string(body)
-	for _, metric := range reqCfg.Metrics {
+	return &response{status: resp.StatusCode, header: resp.Header, body: body}, nil
+}
+
+// statusError reads up to error_body_limit bytes of a failed response,
+// where GitHub usually explains what is wrong, and turns them into an
+// error.
+func (m *Manager) statusError(resp *http.Response, url string) error {
+	err := &httpStatusError{code: resp.StatusCode, err: fmt.Errorf("non-200 status code %d from %s", resp.StatusCode, url)}
+	if m.cfg.ErrorBodyLimit <= 0 {
+		return err
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, int64(m.cfg.ErrorBodyLimit)))
+	if readErr != nil || len(body) == 0 {
+		return err
+	}
+	httpLogger.Debug("Error response body", "url", url, "status", resp.StatusCode, "body", string(body))
+
+	message := gjson.GetBytes(body, "message").String()
+	if message == "" {
+		return err
+	}
+	if doc := gjson.GetBytes(body, "documentation_url").String(); doc != "" {
+		err.err = fmt.Errorf("%w: %s (%s)", err.err, message, doc)
+	} else {
+		err.err = fmt.Errorf("%w: %s", err.err, message)
+	}
+	return err
+}
+
+func gzipBody(body string) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(zw, body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+func (m *Manager) parseSamples(j *job, jsonStr string) []sample {
+	var samples []sample
+	for _, metric := range j.req.Metrics {
 		info, exists := m.metrics[metric.Name]
 		if !exists {
 			continue
 		}
 
-		val := m.parseValue(jsonStr, metric)
-
-		slog.Debug("Parsed metric", "name", metric.Name, "value", val)
-		var labelValues []string
-		for _, key := range info.LabelKeys {
-			if key == "api_path" {
-				labelValues = append(labelValues, reqCfg.ApiPath)
-				continue
+		if metric.GroupBy == "" {
+			if s, ok := m.newSample(j, info, metric, jsonStr, jsonStr, nil); ok {
+				samples = append(samples, s)
 			}
-			// Look up the GJSON path for this label
-			if jsonPath, ok := metric.Labels[key]; ok {
-				res := gjson.Get(jsonStr, jsonPath)
-				labelValues = append(labelValues, res.String())
-			} else {
-				labelValues = append(labelValues, "")
+			continue
+		}
+		for _, g := range groupItems(jsonStr, metric) {
+			if s, ok := m.newSample(j, info, g.metric, jsonStr, g.json, g.labels(metric)); ok {
+				samples = append(samples, s)
 			}
 		}
+	}
+	return samples
+}
 
-		mType := prometheus.GaugeValue
-
-		m, err := prometheus.NewConstMetric(
-			info.Desc,
-			mType,
-			val,
-			labelValues...,
-		)
+// newSample evaluates metric on valueJSON, which is the response body
+// unless the metric is grouped, and resolves its labels on the response
+// body, or on the element the value path matched with relative_labels.
+// extra holds label values that come neither from a path nor from the job,
+// e.g. the group_by key. ok is false when the sample is filtered out.
+func (m *Manager) newSample(j *job, info *MetricInfo, metric config.MetricConfig, jsonStr, valueJSON string, extra map[string]string) (s sample, ok bool) {
+	var val float64
+	switch {
+	case info.expr != nil:
+		v, err := exprValue(info.expr, valueJSON, metric)
 		if err != nil {
-			slog.Error("Failed to create metric", "name", metric.Name, "err", err)
+			logger.Debug("Expression failed, skipping sample", "name", metric.Name, "err", err)
+			return sample{}, false
+		}
+		val = convert(v, metric)
+	case (metric.OnMissing == config.MissingSkip || metric.OnMissing == config.MissingDefault) && missing(query(valueJSON, metric)):
+		if metric.OnMissing == config.MissingSkip || metric.Default == nil {
+			logger.Debug("Path matched nothing, skipping sample", "name", metric.Name, "path", metric.Path)
+			return sample{}, false
+		}
+		val = *metric.Default // already in the exported unit
+	default:
+		val = convert(m.parseValue(valueJSON, metric), metric)
+	}
+
+	logger.Debug("Parsed metric", "name", metric.Name, "value", val)
+	labelJSON := jsonStr
+	if metric.RelativeLabels {
+		labelJSON = gjson.Get(valueJSON, elementPath(metric.Path)).Raw
+	}
+	var labelValues []string
+	for _, key := range info.LabelKeys {
+		if key == "api_path" {
+			labelValues = append(labelValues, j.req.ApiPath)
+			continue
+		}
+		if v, ok := extra[key]; ok {
+			labelValues = append(labelValues, labelValue(v, metric.UnescapeHTML))
 			continue
 		}
+		// Look up the GJSON path for this label
+		if jsonPath, ok := metric.Labels[key]; ok {
+			res := gjson.Get(labelJSON, jsonPath)
+			labelValues = append(labelValues, labelValue(res.String(), metric.UnescapeHTML))
+		} else {
+			labelValues = append(labelValues, labelValue(j.labels[key], metric.UnescapeHTML))
+		}
+	}
+	if !info.filter.keep(info.LabelKeys, labelValues) {
+		logger.Debug("Sample dropped by label filters", "name", metric.Name, "labels", labelValues)
+		return sample{}, false
+	}
+
+	s = sample{
+		info:        info,
+		labelValues: labelValues,
+		value:       val,
+	}
+	if metric.Timestamp != "" {
+		ts, err := time.Parse(time.RFC3339, gjson.Get(labelJSON, metric.Timestamp).String())
+		if err != nil {
+			logger.Debug("No timestamp for sample, exporting it without one", "name", metric.Name, "err", err)
+		}
+		s.timestamp = ts
+	}
+	switch metric.MetricType {
+	case config.MetricCounter:
+		s.value = m.counters.observe(metric.Name, labelValues, val)
+	case config.MetricDelta:
+		s.value = m.counters.delta(metric.Name, labelValues, val)
+	case config.MetricHistogram:
+		s.histogram = newHistogramSample(query(valueJSON, metric), metric.Buckets)
+	}
+	return s, true
+}
 
-		ch <- m
+// convert applies the scale and offset of metric to a parsed value.
+func convert(val float64, metric config.MetricConfig) float64 {
+	if metric.Scale != 0 {
+		val *= metric.Scale
+	}
+	return val + metric.Offset
+}
+
+// missing reports whether a metric path matched nothing, or null.
+func missing(result gjson.Result) bool {
+	return !result.Exists() || result.Type == gjson.Null
+}
+
+// jsonLookup resolves expression paths on jsonStr. Booleans count as 1
+// and 0.
+func jsonLookup(jsonStr string) expr.Lookup {
+	return func(path string) any {
+		res := gjson.Get(jsonStr, path)
+		switch {
+		case !res.Exists() || res.Type == gjson.Null:
+			return nil
+		case res.Type == gjson.Number:
+			return res.Num
+		case res.Type == gjson.True:
+			return 1.0
+		case res.Type == gjson.False:
+			return 0.0
+		}
+		return res.String()
 	}
 }
 
 func (m *Manager) parseValue(jsonStr string, metric config.MetricConfig) float64 {
-	result := gjson.Get(jsonStr, metric.Path)
+	result := query(jsonStr, metric)
+	if metric.Regex != "" {
+		result = gjson.Parse(matchModifier(result.Raw, jsonString(metric.Regex)))
+	}
+	if metric.ValueType == config.TypeChecksum {
+		return checksum(result)
+	}
 
 	if !result.IsArray() {
+		return m.scalarValue(result, metric)
+	}
+	results := result.Array()
+	values := make([]float64, len(results))
+	for i, r := range results {
+		values[i] = m.scalarValue(r, metric)
+	}
+	return aggregate(values, metric.Aggregate)
+}
 
-		if metric.ValueType == config.TypeDate {
-			if result.Type == gjson.String {
-				t, err := time.Parse(time.RFC3339, result.String())
-				if err != nil {
-					slog.Error("Error parsing date for metric", "metric_name", metric.Name, "error", err)
-					return 0
-				}
-				return float64(t.Unix())
-			}
-			// If it's not a string, we can't parse a date
-			return 0
+// exprValue evaluates the expression of metric on jsonStr or, when the
+// metric also has a path selecting an array, on each of its items before
+// aggregating the results. Items the expression fails on are left out.
+func exprValue(e *expr.Expr, jsonStr string, metric config.MetricConfig) (float64, error) {
+	if metric.Path == "" {
+		return e.Eval(jsonLookup(jsonStr))
+	}
+	result := query(jsonStr, metric)
+	if !result.IsArray() {
+		return e.Eval(jsonLookup(result.Raw))
+	}
+	var values []float64
+	for _, item := range result.Array() {
+		v, err := e.Eval(jsonLookup(item.Raw))
+		if err != nil {
+			logger.Debug("Expression failed on an item, skipping it", "name", metric.Name, "err", err)
+			continue
 		}
-		return result.Float()
+		values = append(values, v)
+	}
+	return aggregate(values, metric.Aggregate), nil
+}
+
+// aggregate combines the values of an array. Empty arrays yield 0.
+func aggregate(values []float64, agg config.AggregateType) float64 {
+	if agg == config.AggregateCount {
+		return float64(len(values))
+	}
+	if len(values) == 0 {
+		return 0
 	}
-	var val float64
-	results := result.Array()
 
-	switch metric.Aggregate {
-	case config.AggregateCount:
-		return float64(len(results))
+	val := values[0]
+	switch agg {
+	case config.AggregateFirst:
 	case config.AggregateMax:
-		if len(results) > 0 {
-			val = results[0].Float()
-			for _, r := range results[1:] {
-				if r.Float() > val {
-					val = r.Float()
-				}
-			}
+		for _, v := range values[1:] {
+			val = max(val, v)
 		}
-	case config.AggregateSum: // default
-		fallthrough
-	default:
-		for _, r := range results {
-			val += r.Float()
+	case config.AggregateMin:
+		for _, v := range values[1:] {
+			val = min(val, v)
+		}
+	case config.AggregateAvg:
+		for _, v := range values[1:] {
+			val += v
+		}
+		val /= float64(len(values))
+	default: // sum
+		for _, v := range values[1:] {
+			val += v
 		}
 	}
 	return val
 }
+
+// scalarValue converts a single JSON value through the value_map of the
+// metric, or by parsing dates and booleans according to its value type.
+func (m *Manager) scalarValue(result gjson.Result, metric config.MetricConfig) float64 {
+	if metric.ValueMap != nil {
+		if v, ok := metric.ValueMap[result.String()]; ok {
+			return v
+		}
+		return metric.ValueMapDefault
+	}
+	if metric.ValueType == config.TypeBool {
+		return boolValue(result)
+	}
+	if metric.ValueType == config.TypeSemver {
+		v, err := semver.Parse(result.String())
+		if err != nil {
+			logger.Debug("Not a semantic version", "metric_name", metric.Name, "value", result.String())
+			return 0
+		}
+		return v.Number()
+	}
+	if metric.ValueType == config.TypeDate {
+		// A missing or null date, e.g. an open issue's closed_at, is 0
+		if result.Type == gjson.Null {
+			return 0
+		}
+		t, err := parseDate(result, metric.DateFormat)
+		if err != nil {
+			logger.Error("Error parsing date for metric", "metric_name", metric.Name, "error", err)
+			return 0
+		}
+		return float64(t.Unix())
+	}
+	return result.Float()
+}
+
+// parseDate reads result as a date in format: RFC3339 when empty, epoch
+// seconds or milliseconds for unix and unix_ms, and a Go layout otherwise.
+// Layouts without a zone are read as UTC.
+func parseDate(result gjson.Result, format string) (time.Time, error) {
+	switch format {
+	case config.DateFormatUnix, config.DateFormatUnixMs:
+		var n int64
+		switch result.Type {
+		case gjson.Number:
+			n = result.Int()
+		case gjson.String:
+			var err error
+			if n, err = strconv.ParseInt(result.Str, 10, 64); err != nil {
+				return time.Time{}, err
+			}
+		default:
+			return time.Time{}, fmt.Errorf("not an epoch: %s", result.Raw)
+		}
+		if format == config.DateFormatUnixMs {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	case "":
+		format = time.RFC3339
+	}
+	if result.Type != gjson.String {
+		return time.Time{}, fmt.Errorf("not a date string: %s", result.Raw)
+	}
+	return time.Parse(format, result.String())
+}
+
+// checksum hashes the raw JSON of result, arrays included, to an integer
+// exactly representable as a float64. A missing value is 0.
+func checksum(result gjson.Result) float64 {
+	if !result.Exists() {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(result.Raw))
+	return float64(h.Sum64() & (1<<53 - 1))
+}
+
+// boolValue maps true to 1 and false to 0. Strings such as "true" are
+// parsed, numbers count as true when non-zero, and anything else is false.
+func boolValue(result gjson.Result) float64 {
+	var b bool
+	switch result.Type {
+	case gjson.True:
+		b = true
+	case gjson.String:
+		b, _ = strconv.ParseBool(result.Str)
+	case gjson.Number:
+		b = result.Num != 0
+	}
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -1,15 +1,22 @@
 package collector
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/eleboucher/github-exporter/internal/config"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
 )
 
@@ -46,6 +53,83 @@ func TestParseValue_Date(t *testing.T) {
 	}
 }
 
+func TestParseValue_DateFormat(t *testing.T) {
+	m := &Manager{}
+	want := float64(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC).Unix())
+
+	for _, tt := range []struct {
+		format string
+		json   string
+	}{
+		{"2006-01-02T15:04:05", `{"at": "2024-01-15T10:30:00"}`},
+		{"unix", `{"at": 1705314600}`},
+		{"unix", `{"at": "1705314600"}`},
+		{"unix_ms", `{"at": 1705314600000}`},
+	} {
+		metric := config.MetricConfig{Path: "at", ValueType: config.TypeDate, DateFormat: tt.format}
+		if got := m.parseValue(tt.json, metric); got != want {
+			t.Errorf("%s %s: expected %f, got %f", tt.format, tt.json, want, got)
+		}
+	}
+}
+
+func TestParseSamples_ScaleAndOffset(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app",
+			Metrics: []config.MetricConfig{
+				{Name: "github_repo_size_bytes", Path: "size", Scale: 1024, Help: "Size"},
+				{Name: "github_billing_minutes_seconds", Path: "minutes", Scale: 60, Offset: -30, Help: "Minutes"},
+			},
+		}},
+	}
+	m := NewManager(cfg)
+
+	samples := m.parseSamples(m.jobs[0], `{"size": 2, "minutes": 3}`)
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	if samples[0].value != 2048 {
+		t.Errorf("Expected 2048 bytes, got %f", samples[0].value)
+	}
+	if samples[1].value != 150 {
+		t.Errorf("Expected 150 seconds, got %f", samples[1].value)
+	}
+}
+
+func TestParseSamples_OnMissing(t *testing.T) {
+	fallback := -1.0
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app",
+			Metrics: []config.MetricConfig{
+				{Name: "github_license_zero", Path: "license.key", ValueMap: map[string]float64{"mit": 1}, Help: "License"},
+				{Name: "github_license_skip", Path: "license.key", OnMissing: config.MissingSkip, Help: "License"},
+				{Name: "github_license_default", Path: "license.key", OnMissing: config.MissingDefault, Default: &fallback, Scale: 2, Help: "License"},
+				{Name: "github_stars", Path: "stargazers_count", OnMissing: config.MissingSkip, Help: "Stars"},
+			},
+		}},
+	}
+	m := NewManager(cfg)
+
+	values := map[string]float64{}
+	for _, s := range m.parseSamples(m.jobs[0], `{"license": null, "stargazers_count": 0}`) {
+		values[s.info.Config.Name] = s.value
+	}
+	if len(values) != 3 {
+		t.Errorf("Expected 3 samples, got %v", values)
+	}
+	if _, ok := values["github_license_skip"]; ok {
+		t.Error("Expected no sample for a missing path with on_missing skip")
+	}
+	if values["github_license_default"] != -1 {
+		t.Errorf("Expected the default, unscaled, got %f", values["github_license_default"])
+	}
+	if v, ok := values["github_stars"]; !ok || v != 0 {
+		t.Errorf("Expected an actual 0 to be kept, got %v", values)
+	}
+}
+
 func TestParseValue_AggregateSum(t *testing.T) {
 	m := &Manager{}
 	metric := config.MetricConfig{
@@ -91,6 +175,67 @@ func TestParseValue_AggregateMax(t *testing.T) {
 	}
 }
 
+func TestParseValue_AggregateMin(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{
+		Path:      "#.stargazers_count",
+		Aggregate: config.AggregateMin,
+	}
+
+	jsonStr := `[{"stargazers_count": 10}, {"stargazers_count": 30}, {"stargazers_count": 20}]`
+	val := m.parseValue(jsonStr, metric)
+
+	if val != 10.0 {
+		t.Errorf("Expected 10.0, got %f", val)
+	}
+}
+
+func TestParseValue_AggregateAvg(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{
+		Path:      "#.stargazers_count",
+		Aggregate: config.AggregateAvg,
+	}
+
+	jsonStr := `[{"stargazers_count": 10}, {"stargazers_count": 30}, {"stargazers_count": 20}]`
+	val := m.parseValue(jsonStr, metric)
+
+	if val != 20.0 {
+		t.Errorf("Expected 20.0, got %f", val)
+	}
+}
+
+func TestParseValue_AggregateAvgEmpty(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{
+		Path:      "#.stargazers_count",
+		Aggregate: config.AggregateAvg,
+	}
+
+	val := m.parseValue(`[]`, metric)
+
+	if val != 0 {
+		t.Errorf("Expected 0 for an empty array, got %f", val)
+	}
+}
+
+func TestParseValue_AggregateMinDate(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{
+		Path:      "#.created_at",
+		Aggregate: config.AggregateMin,
+		ValueType: config.TypeDate,
+	}
+
+	jsonStr := `[{"created_at": "2024-03-01T00:00:00Z"}, {"created_at": "2024-01-15T10:30:00Z"}]`
+	val := m.parseValue(jsonStr, metric)
+
+	expectedTime, _ := time.Parse(time.RFC3339, "2024-01-15T10:30:00Z")
+	if val != float64(expectedTime.Unix()) {
+		t.Errorf("Expected %d, got %f", expectedTime.Unix(), val)
+	}
+}
+
 func TestParseValue_InvalidDate(t *testing.T) {
 	m := &Manager{}
 	metric := config.MetricConfig{
@@ -140,8 +285,9 @@ func TestDescribe(t *testing.T) {
 		count++
 	}
 
-	if count != 2 {
-		t.Errorf("Expected 2 descriptors, got %d", count)
+	// Two configured metrics plus github_server_version_info
+	if count != 3 {
+		t.Errorf("Expected 3 descriptors, got %d", count)
 	}
 }
 
@@ -390,3 +536,1016 @@ func TestHTTPTransport_DisableKeepAlives(t *testing.T) {
 		t.Error("Expected DisableKeepAlives to be true")
 	}
 }
+
+func TestFetch_GzipResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			if _, err := io.WriteString(w, `{"followers": 1}`); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		if _, err := io.WriteString(zw, `{"followers": 2}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Errorf("Failed to compress response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		disable bool
+		want    string
+	}{
+		{"compressed", false, `{"followers": 2}`},
+		{"disabled", true, `{"followers": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(&config.Config{GithubAPIURL: server.URL, DisableCompression: tt.disable})
+			body, err := m.fetch(context.Background(), config.RequestConfig{ApiPath: "/users/test"})
+			if err != nil {
+				t.Fatalf("Failed to fetch: %v", err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, body)
+			}
+		})
+	}
+}
+
+func TestCollect_ScheduledRequestServedFromCache(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 7}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:  "/users/test",
+				Interval: "1h",
+				Metrics: []config.MetricConfig{
+					{
+						Name: "github_followers",
+						Path: "followers",
+						Help: "Total followers",
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	m.refresh(context.Background(), m.jobs[0])
+
+	for range 3 {
+		ch := make(chan prometheus.Metric, 10)
+		m.Collect(ch)
+		close(ch)
+
+		metricCount := 0
+		for metric := range ch {
+			metricCount++
+			var metricDTO dto.Metric
+			if err := metric.Write(&metricDTO); err != nil {
+				t.Errorf("Failed to write metric: %v", err)
+			}
+			if metricDTO.GetGauge().GetValue() != 7.0 {
+				t.Errorf("Expected metric value 7.0, got %f", metricDTO.GetGauge().GetValue())
+			}
+		}
+		if metricCount != 1 {
+			t.Errorf("Expected 1 metric, got %d", metricCount)
+		}
+	}
+
+	if hits.Load() != 1 {
+		t.Errorf("Expected 1 request to the API, got %d", hits.Load())
+	}
+}
+
+func TestStart_FetchesInBackground(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 7}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:  "/users/test",
+				Interval: "1h",
+				Metrics: []config.MetricConfig{
+					{
+						Name: "github_followers",
+						Path: "followers",
+						Help: "Total followers",
+					},
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m := NewManager(cfg)
+	m.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		m.mu.RLock()
+		cached := len(m.jobs[0].samples)
+		m.mu.RUnlock()
+		if cached == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected the background fetcher to populate the cache")
+}
+
+func TestPrime_FetchesScheduledRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 7}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:  "/users/test",
+				Interval: "1h",
+				Metrics: []config.MetricConfig{
+					{
+						Name: "github_followers",
+						Path: "followers",
+						Help: "Total followers",
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	m.Prime()
+
+	m.mu.RLock()
+	cached := len(m.jobs[0].samples)
+	version := m.serverVersion
+	m.mu.RUnlock()
+	if cached != 1 {
+		t.Errorf("Expected 1 cached sample, got %d", cached)
+	}
+	if version != dotcomVersion {
+		t.Errorf("Expected server version %q, got %q", dotcomVersion, version)
+	}
+}
+
+func TestCollect_Freshness(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 7}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:   "/users/test",
+				Interval:  "1h",
+				Freshness: true,
+				Metrics: []config.MetricConfig{
+					{
+						Name: "github_followers",
+						Path: "followers",
+						Help: "Total followers",
+					},
+					{
+						Name:       "github_follows",
+						Path:       "followers",
+						Help:       "Follows received",
+						MetricType: config.MetricCounter,
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	before := time.Now().Unix()
+	m.refresh(context.Background(), m.jobs[0])
+
+	ch := make(chan prometheus.Metric, 10)
+	m.Collect(ch)
+	close(ch)
+
+	wanted := []string{"github_followers_last_fetch_timestamp_seconds", "github_follows_total_last_fetch_timestamp_seconds"}
+	found := make(map[string]bool)
+	for metric := range ch {
+		i := slices.IndexFunc(wanted, func(name string) bool {
+			return strings.Contains(metric.Desc().String(), `fqName: "`+name+`"`)
+		})
+		if i < 0 {
+			continue
+		}
+		found[wanted[i]] = true
+		var metricDTO dto.Metric
+		if err := metric.Write(&metricDTO); err != nil {
+			t.Errorf("Failed to write metric: %v", err)
+		}
+		if got := int64(metricDTO.GetGauge().GetValue()); got < before {
+			t.Errorf("Expected a fetch timestamp >= %d, got %d", before, got)
+		}
+	}
+	// Named after the exposed metric, _total suffix of counters included.
+	for _, name := range wanted {
+		if !found[name] {
+			t.Errorf("Expected a %s sample, got %v", name, found)
+		}
+	}
+}
+
+func TestCollect_CompressedPOSTBody(t *testing.T) {
+	query := `{"query": "{ viewer { login } }"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("Expected Content-Encoding gzip, got %q", r.Header.Get("Content-Encoding"))
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read gzip body: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(zr)
+		if string(body) != query {
+			t.Errorf("Expected body %s, got %s", query, body)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"data": {"viewer": {"followers": 3}}}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:      "/graphql",
+				Method:       "POST",
+				Body:         query,
+				CompressBody: true,
+				Metrics: []config.MetricConfig{
+					{
+						Name: "github_followers",
+						Path: "data.viewer.followers",
+						Help: "Followers",
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	ch := make(chan prometheus.Metric, 10)
+	m.Collect(ch)
+	close(ch)
+
+	if len(ch) != 1 {
+		t.Errorf("Expected 1 metric, got %d", len(ch))
+	}
+}
+
+func TestCollect_StaleTTL(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 7}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:  "/users/test",
+				StaleTTL: "1h",
+				Metrics: []config.MetricConfig{
+					{
+						Name: "github_followers",
+						Path: "followers",
+						Help: "Total followers",
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	collect := func() int {
+		ch := make(chan prometheus.Metric, 10)
+		m.Collect(ch)
+		close(ch)
+		return len(ch)
+	}
+
+	if got := collect(); got != 1 {
+		t.Fatalf("Expected 1 metric, got %d", got)
+	}
+
+	failing.Store(true)
+	if got := collect(); got != 1 {
+		t.Errorf("Expected the stale metric to be served, got %d metrics", got)
+	}
+
+	m.mu.Lock()
+	m.jobs[0].lastSuccess = time.Now().Add(-2 * time.Hour)
+	m.mu.Unlock()
+	if got := collect(); got != 0 {
+		t.Errorf("Expected the metric to disappear after stale_ttl, got %d metrics", got)
+	}
+}
+
+func TestFetchURL_ErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		if _, err := io.WriteString(w, `{"message": "Not Found", "documentation_url": "https://docs.github.com/rest"}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{GithubAPIURL: server.URL, ErrorBodyLimit: config.DefaultErrorBodyLimit}
+	m := NewManager(cfg)
+	_, err := m.fetchURL(context.Background(), config.RequestConfig{ApiPath: "/repos/acme/missing"}, server.URL+"/repos/acme/missing")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !strings.Contains(err.Error(), "Not Found (https://docs.github.com/rest)") {
+		t.Errorf("Expected the GitHub message in the error, got %q", err.Error())
+	}
+}
+
+func TestCollect_EmptyResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	metrics := []config.MetricConfig{{Name: "github_stars", Path: "stargazers_count", Help: "Stars"}}
+	tests := []struct {
+		onEmpty  config.EmptyPolicy
+		expected int
+	}{
+		{config.EmptySkip, 0},
+		{config.EmptyZero, 1},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{
+			GithubAPIURL: server.URL,
+			Requests: []config.RequestConfig{
+				{ApiPath: "/repos/acme/app", OnEmpty: tt.onEmpty, Metrics: metrics},
+			},
+		}
+		m := NewManager(cfg)
+		if count := testutil.CollectAndCount(m, "github_stars"); count != tt.expected {
+			t.Errorf("Expected %d series with on_empty %s, got %d", tt.expected, tt.onEmpty, count)
+		}
+	}
+}
+
+func TestCollect_Expr(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"open_issues_count": 9, "stargazers_count": 2, "archived": true}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/repos/acme/app",
+				Metrics: []config.MetricConfig{
+					{Name: "github_issues_per_star", Expr: "open_issues_count / (stargazers_count + 1)", Help: "Issues per star"},
+					{Name: "github_archived", Expr: "archived * 1", Help: "Archived"},
+					{Name: "github_broken", Expr: "open_issues_count / missing", Help: "Division by zero"},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_archived Archived
+# TYPE github_archived gauge
+github_archived{api_path="/repos/acme/app"} 1
+# HELP github_issues_per_star Issues per star
+# TYPE github_issues_per_star gauge
+github_issues_per_star{api_path="/repos/acme/app"} 3
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_issues_per_star", "github_archived", "github_broken"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseValue_Bool(t *testing.T) {
+	m := &Manager{}
+	jsonStr := `{"archived": true, "private": false, "fork": "true", "disabled": null, "repos": [{"private": true}, {"private": false}, {"private": true}]}`
+
+	tests := []struct {
+		path      string
+		aggregate config.AggregateType
+		expected  float64
+	}{
+		{"archived", "", 1},
+		{"private", "", 0},
+		{"fork", "", 1},
+		{"disabled", "", 0},
+		{"missing", "", 0},
+		{"repos.#.private", config.AggregateSum, 2},
+	}
+	for _, tt := range tests {
+		metric := config.MetricConfig{Path: tt.path, ValueType: config.TypeBool, Aggregate: tt.aggregate}
+		if val := m.parseValue(jsonStr, metric); val != tt.expected {
+			t.Errorf("Expected %f for %s, got %f", tt.expected, tt.path, val)
+		}
+	}
+}
+
+func TestCollect_TextResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "application/vnd.github.raw+json" {
+			t.Errorf("Expected the raw media type, got %q", got)
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, "# Coverage\n\nTotal: 87.5%\nFiles: 42\n"); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:        "/repos/acme/app/contents/COVERAGE.md",
+				ResponseFormat: config.FormatText,
+				Metrics: []config.MetricConfig{
+					{Name: "github_coverage", Path: "@this", Regex: `Total: ([0-9.]+)%`, Help: "Coverage"},
+					{Name: "github_covered_files", Path: "@this", Regex: `Files: (\d+)`, Help: "Covered files"},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_coverage Coverage
+# TYPE github_coverage gauge
+github_coverage{api_path="/repos/acme/app/contents/COVERAGE.md"} 87.5
+# HELP github_covered_files Covered files
+# TYPE github_covered_files gauge
+github_covered_files{api_path="/repos/acme/app/contents/COVERAGE.md"} 42
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_coverage", "github_covered_files"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseValue_Regex(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{Path: "body", Regex: `takes (\d+) minutes`}
+
+	if val := m.parseValue(`{"body": "The build takes 12 minutes"}`, metric); val != 12 {
+		t.Errorf("Expected 12, got %f", val)
+	}
+	if val := m.parseValue(`{"body": "No estimate"}`, metric); val != 0 {
+		t.Errorf("Expected 0 without a match, got %f", val)
+	}
+}
+
+func TestParseValue_ValueMap(t *testing.T) {
+	m := &Manager{}
+	valueMap := map[string]float64{"success": 0, "failure": 2, "cancelled": 1}
+	jsonStr := `{"conclusion": "failure", "runs": [{"conclusion": "success"}, {"conclusion": "cancelled"}, {"conclusion": "timed_out"}]}`
+
+	tests := []struct {
+		metric   config.MetricConfig
+		expected float64
+	}{
+		{config.MetricConfig{Path: "conclusion", ValueMap: valueMap}, 2},
+		{config.MetricConfig{Path: "missing", ValueMap: valueMap, ValueMapDefault: -1}, -1},
+		{config.MetricConfig{Path: "runs.#.conclusion", ValueMap: valueMap, ValueMapDefault: 3, Aggregate: config.AggregateMax}, 3},
+		{config.MetricConfig{Path: "runs.#.conclusion", ValueMap: valueMap, Aggregate: config.AggregateSum}, 1},
+	}
+	for _, tt := range tests {
+		if val := m.parseValue(jsonStr, tt.metric); val != tt.expected {
+			t.Errorf("Expected %f for %s, got %f", tt.expected, tt.metric.Path, val)
+		}
+	}
+}
+
+func TestCollect_MaxConcurrent(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 1}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{GithubAPIURL: server.URL, MaxConcurrent: 2}
+	for _, user := range []string{"a", "b", "c", "d", "e", "f"} {
+		cfg.Requests = append(cfg.Requests, config.RequestConfig{
+			ApiPath: "/users/" + user,
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		})
+	}
+
+	m := NewManager(cfg)
+	if got := testutil.CollectAndCount(m, "github_followers"); got != 6 {
+		t.Errorf("Expected 6 series, got %d", got)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", got)
+	}
+}
+
+func TestParseValue_Checksum(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{Path: "required_status_checks", ValueType: config.TypeChecksum}
+
+	before := m.parseValue(`{"required_status_checks": {"strict": true, "contexts": ["ci"]}}`, metric)
+	same := m.parseValue(`{"url": "x", "required_status_checks": {"strict": true, "contexts": ["ci"]}}`, metric)
+	after := m.parseValue(`{"required_status_checks": {"strict": true, "contexts": ["ci", "lint"]}}`, metric)
+
+	if before == 0 {
+		t.Error("Expected a non-zero checksum")
+	}
+	if before != same {
+		t.Errorf("Expected the checksum to ignore other fields, got %f and %f", before, same)
+	}
+	if before == after {
+		t.Error("Expected the checksum to change with the content")
+	}
+	if before != math.Trunc(before) || before >= 1<<53 {
+		t.Errorf("Expected an exact integer, got %f", before)
+	}
+	if val := m.parseValue(`{}`, metric); val != 0 {
+		t.Errorf("Expected 0 for a missing value, got %f", val)
+	}
+}
+
+func TestCollect_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/graphql",
+				Timeout: "50ms",
+				Metrics: []config.MetricConfig{{Name: "github_viewer", Path: "data.viewer", Help: "Viewer"}},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	start := time.Now()
+	_, err := m.fetch(context.Background(), cfg.Requests[0])
+	if err == nil {
+		t.Fatal("Expected a timeout error, got nil")
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("Expected the request to stop after its timeout, took %s", took)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestFetch_RetriesAccepted(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = io.WriteString(w, `{}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `[{"total": 12}]`)
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	req := config.RequestConfig{ApiPath: "/repos/acme/app/stats/contributors", AcceptedRetries: 2, AcceptedDelay: "1ms"}
+
+	body, err := m.fetch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the fetch to succeed once computed, got %v", err)
+	}
+	if string(body) != `[{"total": 12}]` {
+		t.Errorf("Expected the computed body, got %s", body)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Expected 3 calls, got %d", got)
+	}
+
+	calls.Store(0)
+	req.AcceptedRetries = 1
+	if _, err := m.fetch(context.Background(), req); err == nil || !strings.Contains(err.Error(), "202 Accepted") {
+		t.Errorf("Expected a 202 Accepted error after the retries, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 calls, got %d", got)
+	}
+}
+
+func TestFetch_AcceptedRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	req := config.RequestConfig{ApiPath: "/repos/acme/app/stats/contributors", AcceptedRetries: 3, AcceptedDelay: "1h"}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.fetch(ctx, req)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the retry to be cancelled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cancellation to interrupt the retry delay")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 call, got %d", got)
+	}
+}
+
+func TestRefresh_StandbyInterruptsAcceptedRetry(t *testing.T) {
+	retrying := make(chan struct{})
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			_, _ = io.WriteString(w, `{"count": 3}`)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		close(retrying)
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath:         "/repos/acme/app/stats/contributors",
+			Interval:        "1h",
+			AcceptedRetries: 3,
+			AcceptedDelay:   "1h",
+			Metrics:         []config.MetricConfig{{Name: "github_contributors", Path: "count", Help: "Contributors"}},
+		}},
+	})
+	m.refresh(context.Background(), m.jobs[0])
+
+	done := make(chan struct{})
+	go func() {
+		m.refresh(context.Background(), m.jobs[0])
+		close(done)
+	}()
+	<-retrying
+	m.SetStandby(true)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected going on standby to interrupt the retry delay")
+	}
+	if samples := m.cached(m.jobs[0]); len(samples) != 1 || samples[0].value != 3 {
+		t.Errorf("Expected the cached sample to survive the interrupted fetch, got %v", samples)
+	}
+}
+
+func TestCollect_Standby(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"followers": 3}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/test",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+	m := NewManager(cfg)
+	if got := testutil.CollectAndCount(m, "github_followers"); got != 1 {
+		t.Fatalf("Expected 1 series as leader, got %d", got)
+	}
+
+	m.SetStandby(true)
+	if got := testutil.CollectAndCount(m, "github_followers"); got != 1 {
+		t.Errorf("Expected the cached series on standby, got %d", got)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected no API call on standby, got %d calls", got)
+	}
+
+	m.SetStandby(false)
+	testutil.CollectAndCount(m, "github_followers")
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected fetching to resume, got %d calls", got)
+	}
+}
+
+func TestParseValue_Semver(t *testing.T) {
+	m := &Manager{}
+	jsonStr := `{"tag_name": "v1.14.2", "name": "nightly", "releases": [{"tag_name": "v1.9.0"}, {"tag_name": "v1.10.0-rc.1"}]}`
+
+	tests := []struct {
+		path      string
+		aggregate config.AggregateType
+		expected  float64
+	}{
+		{"tag_name", "", 1014002},
+		{"name", "", 0},
+		{"releases.#.tag_name", config.AggregateMax, 1009999.5},
+	}
+	for _, tt := range tests {
+		metric := config.MetricConfig{Path: tt.path, ValueType: config.TypeSemver, Aggregate: tt.aggregate}
+		if val := m.parseValue(jsonStr, metric); val != tt.expected {
+			t.Errorf("Expected %f for %s, got %f", tt.expected, tt.path, val)
+		}
+	}
+}
+
+func TestCollect_Timestamp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"count": 14, "views": [
+			{"timestamp": "2024-01-01T00:00:00Z", "count": 4},
+			{"timestamp": "2024-01-02T00:00:00Z", "count": 7},
+			{"timestamp": "2024-01-03T00:00:00Z", "count": 3}
+		]}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app/traffic/views",
+			Metrics: []config.MetricConfig{
+				{Name: "github_repo_traffic_views", Path: "count", Help: "Views over 14 days"},
+				{
+					Name:      "github_repo_traffic_views_daily",
+					Path:      "views|@reverse|1.count",
+					Timestamp: "views|@reverse|1.timestamp",
+					Help:      "Views on the last complete day",
+				},
+			},
+		}},
+	}
+
+	m := NewManager(cfg)
+	ch := make(chan prometheus.Metric, 10)
+	go func() {
+		m.Collect(ch)
+		close(ch)
+	}()
+
+	want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).UnixMilli()
+	for metric := range ch {
+		var metricDTO dto.Metric
+		if err := metric.Write(&metricDTO); err != nil {
+			t.Errorf("Failed to write metric: %v", err)
+		}
+
+		switch metricDTO.GetGauge().GetValue() {
+		case 7:
+			if metricDTO.GetTimestampMs() != want {
+				t.Errorf("Expected timestamp %d, got %d", want, metricDTO.GetTimestampMs())
+			}
+		case 14:
+			if metricDTO.TimestampMs != nil {
+				t.Errorf("Expected no timestamp on the 14-day total, got %d", metricDTO.GetTimestampMs())
+			}
+		default:
+			t.Errorf("Unexpected value %v", metricDTO.GetGauge().GetValue())
+		}
+	}
+}
+
+func TestCollect_ConstLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"stargazers_count": 12, "name": "app"}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Labels:       map[string]string{"env": "prod", "repo": "ignored"},
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app",
+			Metrics: []config.MetricConfig{{
+				Name:        "github_stars",
+				Path:        "stargazers_count",
+				Labels:      map[string]string{"repo": "name"},
+				ConstLabels: map[string]string{"team": "infra"},
+				Help:        "Stars",
+			}},
+		}},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_stars Stars
+# TYPE github_stars gauge
+github_stars{api_path="/repos/acme/app",env="prod",repo="app",team="infra"} 12
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_stars"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollect_TargetLabels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"stargazers_count": 12}`)
+	}))
+	defer server.Close()
+
+	metrics := []config.MetricConfig{{Name: "github_stars", Path: "stargazers_count", Help: "Stars"}}
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{ApiPath: "/repos/acme/app", Metrics: metrics, TargetLabels: map[string]string{"repo": "acme/app"}},
+			{ApiPath: "/repos/acme/api", Metrics: metrics, TargetLabels: map[string]string{"repo": "acme/api"}},
+		},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_stars Stars
+# TYPE github_stars gauge
+github_stars{api_path="/repos/acme/api",repo="acme/api"} 12
+github_stars{api_path="/repos/acme/app",repo="acme/app"} 12
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_stars"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollect_MetricPrefix(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"stargazers_count": 12, "forks_count": 3}`)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		MetricPrefix: "corp_",
+		Requests: []config.RequestConfig{{
+			ApiPath: "/repos/acme/app",
+			Metrics: []config.MetricConfig{
+				{Name: "github_stars", Path: "stargazers_count", Help: "Stars"},
+				{Name: "github_forks", Path: "forks_count", Help: "Forks"},
+			},
+		}},
+		Computed: []config.ComputedConfig{{Name: "github_forks_per_star", Numerator: "github_forks", Denominator: "github_stars", Help: "Forks per star"}},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP corp_github_stars Stars
+# TYPE corp_github_stars gauge
+corp_github_stars{api_path="/repos/acme/app"} 12
+# HELP corp_github_forks_per_star Forks per star
+# TYPE corp_github_forks_per_star gauge
+corp_github_forks_per_star 0.25
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "corp_github_stars", "corp_github_forks_per_star"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	m := &Manager{cfg: &config.Config{GithubAPIURL: "https://api.github.com"}}
+	for _, tt := range []struct {
+		req  config.RequestConfig
+		want string
+	}{
+		{config.RequestConfig{ApiPath: "/repos/acme/app"}, "https://api.github.com/repos/acme/app"},
+		{config.RequestConfig{ApiPath: "/repos/acme/app", BaseURL: "https://ghe.example.com/api/v3"}, "https://ghe.example.com/api/v3/repos/acme/app"},
+		{config.RequestConfig{ApiPath: "https://www.githubstatus.com/api/v2/status.json"}, "https://www.githubstatus.com/api/v2/status.json"},
+		{config.RequestConfig{ApiPath: "/search/issues?per_page=1", QueryParams: map[string]string{"q": "repo:acme/app is:open", "per_page": "100"}}, "https://api.github.com/search/issues?per_page=100&q=repo%3Aacme%2Fapp+is%3Aopen"},
+	} {
+		if got := m.requestURL(tt.req); got != tt.want {
+			t.Errorf("Expected %s, got %s", tt.want, got)
+		}
+	}
+}
+
+func TestFetch_TokenOnlySentToAPIHost(t *testing.T) {
+	var auth []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		_, _ = io.WriteString(w, `{}`)
+	})
+	api := httptest.NewServer(handler)
+	defer api.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: api.URL, Token: "ghp_global"})
+	for _, req := range []config.RequestConfig{
+		{ApiPath: "/user"},
+		{ApiPath: other.URL + "/status.json"},
+		{ApiPath: "/api/v3/user", BaseURL: other.URL, Token: "ghp_ghes"},
+	} {
+		if _, err := m.fetch(context.Background(), req); err != nil {
+			t.Fatalf("Failed to fetch %s: %v", req.ApiPath, err)
+		}
+	}
+
+	want := []string{"Bearer ghp_global", "", "Bearer ghp_ghes"}
+	if !slices.Equal(auth, want) {
+		t.Errorf("Expected Authorization headers %q, got %q", want, auth)
+	}
+}
@@ -0,0 +1,122 @@
+package collector
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// exposedName is the name a metric is exposed under: counters get the
+// conventional _total suffix.
+func exposedName(metric config.MetricConfig) string {
+	if metric.MetricType == config.MetricCounter && !strings.HasSuffix(metric.Name, "_total") {
+		return metric.Name + "_total"
+	}
+	return metric.Name
+}
+
+// counters turns the values read from the API into monotonic counters.
+// Only increases are accumulated, so a count that goes down (e.g. a deleted
+// release asset) does not look like a counter reset. It also remembers the
+// previous value of delta metrics. With a state_file, both survive restarts.
+type counters struct {
+	mu     sync.Mutex
+	series map[string]*counterSeries
+	path   string // state_file, empty to keep the state in memory only
+	dirty  bool   // series changed since the last save
+}
+
+type counterSeries struct {
+	Name   string   `json:"name"`
+	Labels []string `json:"labels"`
+	Last   float64  `json:"last"`  // last value read from the API
+	Total  float64  `json:"total"` // exposed value of counters
+}
+
+func newCounters() *counters {
+	return &counters{series: make(map[string]*counterSeries)}
+}
+
+func seriesKey(name string, labelValues []string) string {
+	return name + "\xff" + strings.Join(labelValues, "\xff")
+}
+
+// observe records the latest value of a series and returns its counter
+// value. The first observation is exposed as is.
+func (c *counters) observe(name string, labelValues []string, value float64) float64 {
+	key := seriesKey(name, labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dirty = true
+	s, ok := c.series[key]
+	if !ok {
+		c.series[key] = &counterSeries{Name: name, Labels: labelValues, Last: value, Total: value}
+		return value
+	}
+	if value > s.Last {
+		s.Total += value - s.Last
+	}
+	s.Last = value
+	return s.Total
+}
+
+// delta records the latest value of a series and returns its change since
+// the previous observation, 0 for the first one.
+func (c *counters) delta(name string, labelValues []string, value float64) float64 {
+	key := seriesKey(name, labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dirty = true
+	s, ok := c.series[key]
+	if !ok {
+		c.series[key] = &counterSeries{Name: name, Labels: labelValues, Last: value}
+		return 0
+	}
+	change := value - s.Last
+	s.Last = value
+	return change
+}
+
+// histogramSample is the bucketed distribution of an array of values.
+type histogramSample struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64 // cumulative counts by upper bound
+}
+
+// newHistogramSample buckets every value of result, or result itself when it
+// is not an array.
+func newHistogramSample(result gjson.Result, bounds []float64) *histogramSample {
+	if len(bounds) == 0 {
+		bounds = prometheus.DefBuckets
+	}
+	h := &histogramSample{buckets: make(map[float64]uint64, len(bounds))}
+	for _, b := range bounds {
+		h.buckets[b] = 0
+	}
+
+	values := []gjson.Result{result}
+	if result.IsArray() {
+		values = result.Array()
+	} else if !result.Exists() {
+		values = nil
+	}
+	for _, r := range values {
+		v := r.Float()
+		h.count++
+		h.sum += v
+		for _, b := range bounds {
+			if v <= b {
+				h.buckets[b]++
+			}
+		}
+	}
+	return h
+}
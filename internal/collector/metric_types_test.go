@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tidwall/gjson"
+)
+
+func TestCounters_Observe(t *testing.T) {
+	c := newCounters()
+	labels := []string{"/repos/acme/app/releases"}
+
+	for _, tt := range []struct {
+		value float64
+		want  float64
+	}{
+		{10, 10},
+		{15, 15},
+		{12, 15}, // a decrease is not a reset
+		{14, 17},
+	} {
+		if got := c.observe("github_downloads", labels, tt.value); got != tt.want {
+			t.Errorf("observe(%f): expected %f, got %f", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestCounters_Delta(t *testing.T) {
+	c := newCounters()
+	labels := []string{"acme/app"}
+
+	for _, tt := range []struct {
+		value float64
+		want  float64
+	}{
+		{10, 0}, // nothing to compare the first value with
+		{15, 5},
+		{12, -3},
+	} {
+		if got := c.delta("github_stars_gained", labels, tt.value); got != tt.want {
+			t.Errorf("delta(%f): expected %f, got %f", tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestNewHistogramSample(t *testing.T) {
+	h := newHistogramSample(gjson.Parse(`[5, 20, 80, 400]`), []float64{10, 100})
+
+	if h.count != 4 {
+		t.Errorf("Expected count 4, got %d", h.count)
+	}
+	if h.sum != 505 {
+		t.Errorf("Expected sum 505, got %f", h.sum)
+	}
+	if h.buckets[10] != 1 || h.buckets[100] != 3 {
+		t.Errorf("Unexpected buckets: %v", h.buckets)
+	}
+}
+
+func TestCollect_CounterAndHistogram(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `[{"additions": 5, "downloads": 3}, {"additions": 250, "downloads": 4}]`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/pulls",
+				Metrics: []config.MetricConfig{
+					{
+						Name:       "github_downloads",
+						Path:       "#.downloads",
+						Help:       "Downloads",
+						MetricType: config.MetricCounter,
+					},
+					{
+						Name:       "github_pull_request_additions",
+						Path:       "#.additions",
+						Help:       "Lines added per pull request",
+						MetricType: config.MetricHistogram,
+						Buckets:    []float64{10, 100},
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	expected := `
+# HELP github_downloads_total Downloads
+# TYPE github_downloads_total counter
+github_downloads_total{api_path="/pulls"} 7
+# HELP github_pull_request_additions Lines added per pull request
+# TYPE github_pull_request_additions histogram
+github_pull_request_additions_bucket{api_path="/pulls",le="10"} 1
+github_pull_request_additions_bucket{api_path="/pulls",le="100"} 1
+github_pull_request_additions_bucket{api_path="/pulls",le="+Inf"} 2
+github_pull_request_additions_sum{api_path="/pulls"} 255
+github_pull_request_additions_count{api_path="/pulls"} 2
+`
+	if err := testutil.CollectAndCompare(m, strings.NewReader(expected), "github_downloads_total", "github_pull_request_additions"); err != nil {
+		t.Error(err)
+	}
+}
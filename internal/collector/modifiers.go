@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// now is swapped out in tests.
+var now = time.Now
+
+func init() {
+	gjson.AddModifier("since", sinceModifier)
+	gjson.AddModifier("base64", base64Modifier)
+	gjson.AddModifier("match", matchModifier)
+	gjson.AddModifier("entries", entriesModifier)
+}
+
+// sinceModifier keeps the elements of an array whose RFC3339 timestamp at
+// arg.field is within arg.duration of now, e.g.
+//
+//	items.nodes|@since:{"field":"createdAt","duration":"168h"}
+func sinceModifier(json, arg string) string {
+	args := gjson.Parse(arg)
+	field := args.Get("field").String()
+	window, err := time.ParseDuration(args.Get("duration").String())
+	if field == "" || err != nil {
+		return ""
+	}
+	cutoff := now().Add(-window)
+
+	var kept []string
+	gjson.Parse(json).ForEach(func(_, elem gjson.Result) bool {
+		t, err := time.Parse(time.RFC3339, elem.Get(field).String())
+		if err == nil && !t.Before(cutoff) {
+			kept = append(kept, elem.Raw)
+		}
+		return true
+	})
+	return "[" + strings.Join(kept, ",") + "]"
+}
+
+// base64Modifier decodes a base64 string, such as the content of a file
+// returned by the contents API, into a JSON string. Chain @fromstr to read
+// a JSON file:
+//
+//	content|@base64|@fromstr|version
+func base64Modifier(jsonStr, arg string) string {
+	encoded := strings.Join(strings.Fields(gjson.Parse(jsonStr).String()), "")
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	return jsonString(string(decoded))
+}
+
+// matchModifier returns the first capture group of the regular expression
+// arg in a string, or the whole match when it has no group, e.g.
+//
+//	content|@base64|@match:"coverage: ([0-9.]+)%"
+func matchModifier(jsonStr, arg string) string {
+	re, err := regexp.Compile(gjson.Parse(arg).String())
+	if err != nil {
+		return ""
+	}
+	match := re.FindStringSubmatch(gjson.Parse(jsonStr).String())
+	switch len(match) {
+	case 0:
+		return ""
+	case 1:
+		return jsonString(match[0])
+	}
+	return jsonString(match[1])
+}
+
+// entriesModifier turns an object into an array of {"key", "value"} pairs,
+// so that maps such as the billing minutes per runner OS can be grouped
+// by key:
+//
+//	minutes_used_breakdown|@entries.#.value
+func entriesModifier(jsonStr, _ string) string {
+	obj := gjson.Parse(jsonStr)
+	if !obj.IsObject() {
+		return ""
+	}
+	var entries []string
+	obj.ForEach(func(key, value gjson.Result) bool {
+		entries = append(entries, `{"key":`+jsonString(key.String())+`,"value":`+value.Raw+`}`)
+		return true
+	})
+	return "[" + strings.Join(entries, ",") + "]"
+}
+
+func jsonString(s string) string {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestSinceModifier(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	m := &Manager{}
+	jsonStr := `{"items": [
+		{"createdAt": "2024-01-15T08:00:00Z", "content": {"closedAt": null}},
+		{"createdAt": "2024-01-10T08:00:00Z", "content": {"closedAt": "2024-01-15T08:00:00Z"}},
+		{"createdAt": "2023-12-01T08:00:00Z", "content": {"closedAt": "2023-12-02T08:00:00Z"}}
+	]}`
+
+	added := m.parseValue(jsonStr, config.MetricConfig{
+		Path:      `items|@since:{"field":"createdAt","duration":"168h"}`,
+		Aggregate: config.AggregateCount,
+	})
+	if added != 2 {
+		t.Errorf("Expected 2 items added in the last week, got %f", added)
+	}
+
+	closed := m.parseValue(jsonStr, config.MetricConfig{
+		Path:      `items|@since:{"field":"content.closedAt","duration":"24h"}`,
+		Aggregate: config.AggregateCount,
+	})
+	if closed != 1 {
+		t.Errorf("Expected 1 item closed in the last day, got %f", closed)
+	}
+}
+
+func TestSinceModifier_InvalidArgs(t *testing.T) {
+	if got := sinceModifier(`[{"createdAt": "2024-01-15T08:00:00Z"}]`, `{"field":"createdAt","duration":"a week"}`); got != "" {
+		t.Errorf("Expected empty result for invalid duration, got %s", got)
+	}
+}
+
+func TestBase64AndMatchModifiers(t *testing.T) {
+	m := &Manager{}
+	// {"message":"87.5%"} and "version = 1.42.0\n", base64-encoded with the
+	// line breaks the contents API inserts.
+	badge := `{"content": "eyJtZXNzYWdl\nIjoiODcuNSUifQ==\n", "encoding": "base64"}`
+	version := `{"content": "dmVyc2lvbiA9IDEuNDIuMAo=\n", "encoding": "base64"}`
+
+	tests := []struct {
+		jsonStr  string
+		path     string
+		expected float64
+	}{
+		{badge, `content|@base64|@fromstr|message|@match:"([0-9.]+)%"`, 87.5},
+		{version, `content|@base64|@match:"version = (\\d+)\\."`, 1},
+		{version, `content|@base64|@match:"\\d+\\.(\\d+)"`, 42},
+		{version, `content|@base64|@match:"missing (\\d+)"`, 0},
+	}
+	for _, tt := range tests {
+		if got := m.parseValue(tt.jsonStr, config.MetricConfig{Path: tt.path}); got != tt.expected {
+			t.Errorf("Expected %f for %s, got %f", tt.expected, tt.path, got)
+		}
+	}
+}
+
+func TestBase64Modifier_Invalid(t *testing.T) {
+	if got := base64Modifier(`"not base64!"`, ""); got != "" {
+		t.Errorf("Expected empty result for invalid base64, got %s", got)
+	}
+}
+
+func TestEntriesModifier(t *testing.T) {
+	cfg := &config.Config{
+		Requests: []config.RequestConfig{{
+			ApiPath: "/orgs/acme/settings/billing/actions",
+			Metrics: []config.MetricConfig{{
+				Name:       "github_actions_minutes_used_by_os",
+				Path:       "minutes_used_breakdown|@entries.#.value",
+				Help:       "Minutes per runner OS",
+				GroupBy:    "key",
+				GroupLabel: "os",
+			}},
+		}},
+	}
+	m := NewManager(cfg)
+
+	billing := `{"total_minutes_used": 305, "minutes_used_breakdown": {"UBUNTU": 205, "MACOS": 10, "WINDOWS": 90}}`
+	samples := m.parseSamples(m.jobs[0], billing)
+	if len(samples) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(samples))
+	}
+
+	want := map[string]float64{"UBUNTU": 205, "MACOS": 10, "WINDOWS": 90}
+	for _, s := range samples {
+		os := labelValuesFor(s, []string{"os"})[0]
+		if s.value != want[os] {
+			t.Errorf("Expected %f minutes on %s, got %f", want[os], os, s.value)
+		}
+	}
+	if got := entriesModifier(`[1, 2]`, ""); got != "" {
+		t.Errorf("Expected empty result for an array, got %s", got)
+	}
+}
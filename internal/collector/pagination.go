@@ -0,0 +1,175 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+const scimPageSize = 100
+
+// fetchPages walks a paginated REST endpoint and returns the first page with
+// its items replaced by the items of every page (or a plain array when the
+// endpoint returns top-level arrays).
+func (m *Manager) fetchPages(ctx context.Context, reqCfg config.RequestConfig) ([]byte, error) {
+	p := reqCfg.Paginate
+	if p.Type == config.PaginateCount {
+		return m.countItems(ctx, reqCfg)
+	}
+
+	var (
+		first      []byte
+		items      = []json.RawMessage{}
+		next       = m.requestURL(reqCfg)
+		startIndex = 1
+	)
+	for page := 0; next != ""; page++ {
+		if p.MaxPages > 0 && page >= p.MaxPages {
+			logger.Warn("Reached max_pages, results are truncated", append(logArgs(reqCfg), "max_pages", p.MaxPages)...)
+			break
+		}
+
+		pageURL := next
+		if p.Type == config.PaginateSCIM {
+			pageURL = withQuery(next, map[string]string{
+				"startIndex": strconv.Itoa(startIndex),
+				"count":      strconv.Itoa(scimPageSize),
+			})
+		}
+
+		resp, err := m.fetchURL(ctx, reqCfg, pageURL)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = resp.body
+		}
+
+		pageItems := gjson.ParseBytes(resp.body)
+		if p.ItemsPath != "" {
+			pageItems = gjson.GetBytes(resp.body, p.ItemsPath)
+		}
+		results := pageItems.Array()
+		for _, item := range results {
+			items = append(items, json.RawMessage(item.Raw))
+		}
+
+		switch p.Type {
+		case config.PaginateSCIM:
+			startIndex += len(results)
+			total := gjson.GetBytes(resp.body, "totalResults").Int()
+			if len(results) == 0 || int64(startIndex) > total {
+				next = ""
+			}
+		default:
+			next = nextLink(resp.header.Get("Link"))
+			if p.Since != nil && len(results) > 0 && olderThan(results[len(results)-1], p.Since) {
+				next = ""
+			}
+			if next != "" && !sameOrigin(m.requestURL(reqCfg), next) {
+				logger.Warn("Not following pagination link to another host", append(logArgs(reqCfg), "link", next)...)
+				next = ""
+			}
+		}
+	}
+
+	if p.ItemsPath == "" {
+		return json.Marshal(items)
+	}
+	return setJSONPath(first, p.ItemsPath, items)
+}
+
+// olderThan reports whether the timestamp of item falls before the window
+// of since, so that later pages of a newest-first list can be skipped.
+func olderThan(item gjson.Result, since *config.PaginateSince) bool {
+	window, err := time.ParseDuration(since.Duration)
+	if err != nil {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339, item.Get(since.Field).String())
+	return err == nil && t.Before(now().Add(-window))
+}
+
+// countItems counts the items of a list endpoint without fetching every
+// page: with one item per page, the page number of the rel="last" link is
+// the item count. The body is replaced by {"count": N}.
+func (m *Manager) countItems(ctx context.Context, reqCfg config.RequestConfig) ([]byte, error) {
+	resp, err := m.fetchURL(ctx, reqCfg, withQuery(m.requestURL(reqCfg), map[string]string{"per_page": "1"}))
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if last := linkRel(resp.header.Get("Link"), "last"); last != "" {
+		u, err := url.Parse(last)
+		if err != nil {
+			return nil, fmt.Errorf("parsing last page link: %w", err)
+		}
+		if count, err = strconv.ParseInt(u.Query().Get("page"), 10, 64); err != nil {
+			return nil, fmt.Errorf("no page number in last page link %q", last)
+		}
+	} else {
+		// A single page holds every item.
+		items := gjson.ParseBytes(resp.body)
+		if reqCfg.Paginate.ItemsPath != "" {
+			items = gjson.GetBytes(resp.body, reqCfg.Paginate.ItemsPath)
+		}
+		count = int64(len(items.Array()))
+	}
+	return json.Marshal(map[string]int64{"count": count})
+}
+
+// nextLink extracts the rel="next" target of a Link header.
+func nextLink(header string) string {
+	return linkRel(header, "next")
+}
+
+// linkRel extracts the target of the given relation from a Link header.
+func linkRel(header, rel string) string {
+	for link := range strings.SplitSeq(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for param := range strings.SplitSeq(params, ";") {
+			if strings.TrimSpace(param) == `rel="`+rel+`"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// sameOrigin reports whether link points at the same host as base, so
+// tokens are never sent elsewhere.
+func sameOrigin(base, link string) bool {
+	b, err := url.Parse(base)
+	if err != nil {
+		return false
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == b.Scheme && u.Host == b.Host
+}
+
+func withQuery(rawURL string, params map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, v := range params {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
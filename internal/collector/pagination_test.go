@@ -0,0 +1,225 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestFetchPages_LinkHeaderWithAfterCursor(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer enterprise-token" {
+			t.Errorf("Expected request token to override the global one, got '%s'", r.Header.Get("Authorization"))
+		}
+
+		body := `[{"action": "repo.create"}, {"action": "team.add_member"}]`
+		if r.URL.Query().Get("after") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/audit-log?after=MS42&per_page=2>; rel="next"`, server.URL))
+		} else {
+			body = `[{"action": "repo.create"}]`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Token:        "global-token",
+		Requests: []config.RequestConfig{
+			{
+				ApiPath:  "/orgs/acme/audit-log?per_page=2",
+				Token:    "enterprise-token",
+				Paginate: &config.PaginateConfig{Type: config.PaginateLink},
+				Metrics: []config.MetricConfig{
+					{
+						Name:       "github_audit_log_events",
+						Path:       "#",
+						GroupBy:    "action",
+						GroupLabel: "action",
+						Help:       "Audit log events per action",
+					},
+				},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	samples, err := m.scrape(context.Background(), m.jobs[0])
+	if err != nil {
+		t.Fatalf("Failed to scrape: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, s := range samples {
+		got[strings.Join(s.labelValues, ",")] = s.value
+	}
+	want := map[string]float64{"repo.create,/orgs/acme/audit-log?per_page=2": 2, "team.add_member,/orgs/acme/audit-log?per_page=2": 1}
+	if !maps.Equal(got, want) {
+		t.Errorf("Expected events per action across pages %v, got %v", want, got)
+	}
+}
+
+func TestFetchPages_SCIM(t *testing.T) {
+	const total = 5
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("startIndex"))
+		if r.URL.Query().Get("count") != strconv.Itoa(scimPageSize) {
+			t.Errorf("Expected count=%d, got %s", scimPageSize, r.URL.Query().Get("count"))
+		}
+
+		// Serve two users per page regardless of the requested count
+		resources := ""
+		for i := start; i < start+2 && i <= total; i++ {
+			if resources != "" {
+				resources += ","
+			}
+			resources += fmt.Sprintf(`{"userName": "user%d", "active": %t}`, i, i != 3)
+		}
+
+		w.Header().Set("Content-Type", "application/scim+json")
+		w.WriteHeader(http.StatusOK)
+		body := fmt.Sprintf(`{"totalResults": %d, "startIndex": %d, "itemsPerPage": 2, "Resources": [%s]}`, total, start, resources)
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	body, err := m.fetchPages(context.Background(), config.RequestConfig{
+		ApiPath:  "/scim/v2/organizations/acme/Users",
+		Paginate: &config.PaginateConfig{Type: config.PaginateSCIM, ItemsPath: "Resources"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch pages: %v", err)
+	}
+
+	val := m.parseValue(string(body), config.MetricConfig{Path: "Resources.#(active==true)#", Aggregate: config.AggregateCount})
+	if val != 4 {
+		t.Errorf("Expected 4 active users, got %f", val)
+	}
+}
+
+func TestFetchPages_DoesNotFollowForeignLinks(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Link", `<https://evil.example.com/page2>; rel="next"`)
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `[1]`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	if _, err := m.fetchPages(context.Background(), config.RequestConfig{ApiPath: "/items", Paginate: &config.PaginateConfig{Type: config.PaginateLink}}); err != nil {
+		t.Fatalf("Failed to fetch pages: %v", err)
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	header := `<https://api.github.com/orgs/acme/audit-log?after=MS42>; rel="next", <https://api.github.com/orgs/acme/audit-log?before=MS40>; rel="prev"`
+	if got := nextLink(header); got != "https://api.github.com/orgs/acme/audit-log?after=MS42" {
+		t.Errorf("Unexpected next link: %s", got)
+	}
+	if got := nextLink(`<https://api.github.com/x?page=1>; rel="first"`); got != "" {
+		t.Errorf("Expected no next link, got %s", got)
+	}
+}
+
+func TestFetchPages_Count(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "1" {
+			t.Errorf("Expected one item per page, got '%s'", r.URL.RawQuery)
+		}
+		if r.URL.Path == "/repos/acme/app/tags" {
+			w.Header().Set("Link", fmt.Sprintf(`<%[1]s/repositories/1/tags?per_page=1&page=2>; rel="next", <%[1]s/repositories/1/tags?per_page=1&page=57>; rel="last"`, server.URL))
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `[{"name": "v1.0.0"}]`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	tests := map[string]float64{
+		"/repos/acme/app/tags":     57,
+		"/repos/acme/app/branches": 1,
+	}
+	for path, expected := range tests {
+		body, err := m.fetchPages(context.Background(), config.RequestConfig{ApiPath: path, Paginate: &config.PaginateConfig{Type: config.PaginateCount}})
+		if err != nil {
+			t.Fatalf("Failed to count %s: %v", path, err)
+		}
+		if val := m.parseValue(string(body), config.MetricConfig{Path: "count"}); val != expected {
+			t.Errorf("Expected %v items for %s, got %v", expected, path, val)
+		}
+	}
+}
+
+func TestFetchPages_StopsOutsideSince(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	pages := map[string]string{
+		"":  `[{"created_at": "2024-01-14T00:00:00Z"}, {"created_at": "2024-01-10T00:00:00Z"}]`,
+		"2": `[{"created_at": "2024-01-09T00:00:00Z"}, {"created_at": "2024-01-01T00:00:00Z"}]`,
+		"3": `[{"created_at": "2023-12-01T00:00:00Z"}]`,
+	}
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		page := r.URL.Query().Get("page")
+		next := map[string]string{"": "2", "2": "3"}[page]
+		if next != "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/acme/app/forks?page=%s>; rel="next"`, server.URL, next))
+		}
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, pages[page]); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	body, err := m.fetchPages(context.Background(), config.RequestConfig{
+		ApiPath: "/repos/acme/app/forks",
+		Paginate: &config.PaginateConfig{
+			Type:  config.PaginateLink,
+			Since: &config.PaginateSince{Field: "created_at", Duration: "168h"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch pages: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+	val := m.parseValue(string(body), config.MetricConfig{Path: `@since:{"field":"created_at","duration":"168h"}`, Aggregate: config.AggregateCount})
+	if val != 3 {
+		t.Errorf("Expected 3 forks within the window, got %v", val)
+	}
+}
@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeTargetRE matches user and organization logins. It also keeps the
+// target from escaping the API path or the JSON body it is rendered into.
+var probeTargetRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ServeProbe handles /probe?target=<login> (user= is accepted as well): the
+// probe request templates are run for that login and their metrics are
+// returned, blackbox exporter style, so one deployment can serve many
+// accounts through Prometheus relabeling.
+func (m *Manager) ServeProbe(w http.ResponseWriter, r *http.Request) {
+	if len(m.cfg.Probe.Requests) == 0 {
+		http.Error(w, "no probe requests configured", http.StatusNotFound)
+		return
+	}
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		target = r.URL.Query().Get("user")
+	}
+	if !probeTargetRE.MatchString(target) {
+		http.Error(w, "target parameter is missing or invalid", http.StatusBadRequest)
+		return
+	}
+
+	probeCfg := *m.cfg
+	probeCfg.Requests = nil
+	probeCfg.Discovery = nil
+	probeCfg.Computed = nil
+	// The state file belongs to the main Manager, a probe must neither
+	// read nor overwrite it.
+	probeCfg.StateFile = ""
+	for _, tmpl := range m.cfg.Probe.Requests {
+		req, err := expandTemplate(tmpl, map[string]string{"Target": target})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Probes are answered with fresh data, never from a background cache.
+		req.Interval = ""
+		probeCfg.Requests = append(probeCfg.Requests, req)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m.probeManager(&probeCfg))
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// probeManager returns a Manager fetching the requests of cfg with the HTTP
+// client, token, concurrency and search limits and self-metrics of m, so a
+// probe neither builds a transport nor registers secrets again. Counters
+// start over on every probe.
+func (m *Manager) probeManager(cfg *config.Config) *Manager {
+	p := &Manager{
+		cfg:        cfg,
+		client:     m.client,
+		metrics:    make(map[string]*MetricInfo),
+		token:      m.token,
+		tokenFile:  m.tokenFile,
+		semaphore:  m.semaphore,
+		counters:   newCounters(),
+		windows:    newWindows(),
+		self:       m.self,
+		discovered: make(map[int][]*job),
+		inflight:   newFetchGroup(false),
+		search:     m.search,
+	}
+	m.activeMu.Lock()
+	p.active = m.active
+	m.activeMu.Unlock()
+
+	p.initDescriptors()
+	for _, req := range cfg.Requests {
+		p.jobs = append(p.jobs, newJob(req, req.TargetLabels))
+	}
+	return p
+}
@@ -0,0 +1,134 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestServeProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/octocat" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 42}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Probe: config.ProbeConfig{
+			Requests: []config.RequestConfig{{
+				ApiPath:  "/users/{{ .Target }}",
+				Interval: "1h",
+				Metrics: []config.MetricConfig{{
+					Name: "github_followers",
+					Path: "followers",
+					Help: "Total followers",
+				}},
+			}},
+		},
+	}
+	m := NewManager(cfg)
+
+	rec := httptest.NewRecorder()
+	m.ServeProbe(rec, httptest.NewRequest(http.MethodGet, "/probe?target=octocat", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `github_followers{api_path="/users/octocat"} 42`) {
+		t.Errorf("Expected the probed metric, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestServeProbe_LeavesStateFileAlone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := io.WriteString(w, `{"followers": 42}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	state := `{"version":1,"series":[{"name":"github_followers","labels":["/users/octocat"],"last":40,"total":100}]}`
+	if err := os.WriteFile(path, []byte(state), 0600); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		StateFile:    path,
+		Probe: config.ProbeConfig{
+			Requests: []config.RequestConfig{{
+				ApiPath: "/users/{{ .Target }}",
+				Metrics: []config.MetricConfig{{
+					Name:       "github_followers",
+					Path:       "followers",
+					Help:       "Total followers",
+					MetricType: config.MetricCounter,
+				}},
+			}},
+		},
+	}
+	m := NewManager(cfg)
+
+	rec := httptest.NewRecorder()
+	m.ServeProbe(rec, httptest.NewRequest(http.MethodGet, "/probe?target=octocat", nil))
+
+	if !strings.Contains(rec.Body.String(), `github_followers_total{api_path="/users/octocat"} 42`) {
+		t.Errorf("Expected the probe to start from fresh counters, got:\n%s", rec.Body.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	if string(data) != state {
+		t.Errorf("Expected the probe to leave the state file untouched, got %s", data)
+	}
+}
+
+func TestServeProbe_InvalidTarget(t *testing.T) {
+	cfg := &config.Config{
+		Probe: config.ProbeConfig{
+			Requests: []config.RequestConfig{{ApiPath: "/users/{{ .Target }}"}},
+		},
+	}
+	m := NewManager(cfg)
+
+	for _, query := range []string{"", "?target=../orgs/acme", `?user=a"b`} {
+		rec := httptest.NewRecorder()
+		m.ServeProbe(rec, httptest.NewRequest(http.MethodGet, "/probe"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestProbeManager_SharesClient(t *testing.T) {
+	m := NewManager(&config.Config{Token: "token"})
+	p := m.probeManager(&config.Config{
+		Requests: []config.RequestConfig{{ApiPath: "/users/octocat"}},
+	})
+
+	if p.client != m.client || p.self != m.self || p.semaphore != m.semaphore || p.search != m.search {
+		t.Error("Expected the probe to reuse the HTTP client, self-metrics and limits")
+	}
+	if p.counters == m.counters {
+		t.Error("Expected the probe to start with its own counters")
+	}
+	if p.githubToken() != "token" || len(p.jobs) != 1 {
+		t.Errorf("Expected the token and one job, got %q and %d jobs", p.githubToken(), len(p.jobs))
+	}
+}
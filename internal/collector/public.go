@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"regexp"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// authRequiredRE matches REST endpoints GitHub only serves to authenticated
+// callers, even for public repositories.
+var authRequiredRE = regexp.MustCompile(`^/(user|notifications|installation)(/|\?|$)|/(traffic|dependabot|code-scanning|secret-scanning|audit-log|hooks|collaborators|teams|scim|copilot|billing)(/|\?|$)|/actions/(runners|secrets|variables|permissions)(/|\?|$)|/members(/|\?|$)`)
+
+// warnAuthRequired logs the requests that cannot work in public mode.
+func warnAuthRequired(cfg *config.Config) {
+	reqs := append([]config.RequestConfig(nil), cfg.Requests...)
+	for _, d := range cfg.Discovery {
+		reqs = append(reqs, d.Requests...)
+	}
+	for _, req := range reqs {
+		switch {
+		case isGraphQL(req):
+			logger.Warn("The GraphQL API requires a token, this request will fail in public mode", logArgs(req)...)
+		case authRequiredRE.MatchString(req.ApiPath):
+			logger.Warn("This endpoint requires a token, it will likely fail in public mode", logArgs(req)...)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package collector
+
+import "testing"
+
+func TestAuthRequiredRE(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/user":                               true,
+		"/user/repos?per_page=100":            true,
+		"/users/octocat":                      false,
+		"/repos/acme/app/traffic/views":       true,
+		"/repos/acme/app/actions/runs":        false,
+		"/orgs/acme/actions/runners":          true,
+		"/orgs/acme/members":                  true,
+		"/orgs/acme/public_members":           false,
+		"/orgs/acme/settings/billing/actions": true,
+		"/repos/acme/app/releases":            false,
+	} {
+		if got := authRequiredRE.MatchString(path); got != want {
+			t.Errorf("Expected %t for %s, got %t", want, path, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package collector
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// query evaluates the path of metric. With sort_by or limit, the array the
+// path iterates over (the part before the first "#.") is sorted and
+// truncated first, e.g. "workflow_runs.#.run_duration_ms" with
+// sort_by "-created_at" and limit 50 only reads the 50 most recent runs.
+func query(jsonStr string, metric config.MetricConfig) gjson.Result {
+	if metric.SortBy == "" && metric.Limit == 0 {
+		return gjson.Get(jsonStr, metric.Path)
+	}
+	items, tail, ok := arrayItems(jsonStr, metric)
+	if !ok {
+		return gjson.Get(jsonStr, metric.Path)
+	}
+	return gjson.Get(itemsJSON(items), tail)
+}
+
+// arrayItems returns the sorted and truncated items of the array the path
+// of metric iterates over, along with the rest of the path to evaluate on
+// them ("@this" when the path is the array itself). ok is false when the
+// path does not go through an array.
+func arrayItems(jsonStr string, metric config.MetricConfig) (items []gjson.Result, tail string, ok bool) {
+	base, tail := metric.Path, "@this"
+	switch {
+	case metric.Path == "#" || strings.HasPrefix(metric.Path, "#."):
+		base, tail = "@this", metric.Path
+	case strings.HasSuffix(metric.Path, ".#"):
+		base, tail = strings.TrimSuffix(metric.Path, ".#"), "#"
+	default:
+		if before, after, found := strings.Cut(metric.Path, ".#."); found {
+			base, tail = before, "#."+after
+		}
+	}
+
+	array := gjson.Get(jsonStr, base)
+	if !array.IsArray() {
+		return nil, "", false
+	}
+	items = array.Array()
+
+	if metric.SortBy != "" {
+		key, desc := strings.CutPrefix(metric.SortBy, "-")
+		sortKey := func(r gjson.Result) gjson.Result {
+			if key == "" || key == "@this" {
+				return r
+			}
+			return r.Get(key)
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := sortKey(items[i]), sortKey(items[j])
+			if desc {
+				return b.Less(a, true)
+			}
+			return a.Less(b, true)
+		})
+	}
+	if metric.Limit > 0 && len(items) > metric.Limit {
+		items = items[:metric.Limit]
+	}
+	return items, tail, true
+}
+
+// itemsJSON encodes items as a JSON array.
+func itemsJSON(items []gjson.Result) string {
+	raw := make([]string, len(items))
+	for i, item := range items {
+		raw[i] = item.Raw
+	}
+	return rawArray(raw)
+}
+
+func rawArray(raw []string) string {
+	return "[" + strings.Join(raw, ",") + "]"
+}
+
+// elementPath returns the path of the array element a value path matched:
+// everything up to the last top-level "#(...)" query, or the parent object
+// when the path has no query. "#(type==\"PushEvent\").created_at" yields
+// "#(type==\"PushEvent\")".
+func elementPath(path string) string {
+	end, depth, quoted := -1, 0, false
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '(' && (depth > 0 || (i > 0 && path[i-1] == '#')):
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				end = i + 1
+			}
+		}
+	}
+	if end > 0 {
+		return path[:end]
+	}
+	if parent, _, found := cutLastDot(path); found {
+		return parent
+	}
+	return "@this"
+}
+
+// cutLastDot splits path around its last unescaped dot.
+func cutLastDot(path string) (before, after string, found bool) {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '.' && (i == 0 || path[i-1] != '\\') {
+			return path[:i], path[i+1:], true
+		}
+	}
+	return "", path, false
+}
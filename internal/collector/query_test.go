@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+const runsJSON = `{"workflow_runs": [
+	{"created_at": "2024-01-01T00:00:00Z", "duration": 10},
+	{"created_at": "2024-03-01T00:00:00Z", "duration": 30},
+	{"created_at": "2024-02-01T00:00:00Z", "duration": 20}
+]}`
+
+func TestQuery_SortAndLimit(t *testing.T) {
+	got := query(runsJSON, config.MetricConfig{
+		Path:   "workflow_runs.#.duration",
+		SortBy: "-created_at",
+		Limit:  2,
+	})
+	if got.Raw != "[30,20]" {
+		t.Errorf("Expected [30,20], got %s", got.Raw)
+	}
+}
+
+func TestQuery_Ascending(t *testing.T) {
+	got := query(runsJSON, config.MetricConfig{
+		Path:   "workflow_runs.#.duration",
+		SortBy: "created_at",
+	})
+	if got.Raw != "[10,20,30]" {
+		t.Errorf("Expected [10,20,30], got %s", got.Raw)
+	}
+}
+
+func TestQuery_CountAfterLimit(t *testing.T) {
+	got := query(runsJSON, config.MetricConfig{Path: "workflow_runs.#", Limit: 2})
+	if got.Int() != 2 {
+		t.Errorf("Expected 2, got %s", got.Raw)
+	}
+}
+
+func TestParseValue_AverageOfMostRecent(t *testing.T) {
+	m := &Manager{}
+	metric := config.MetricConfig{
+		Path:      "workflow_runs.#.duration",
+		SortBy:    "-created_at",
+		Limit:     2,
+		Aggregate: config.AggregateSum,
+	}
+
+	if val := m.parseValue(runsJSON, metric); val != 50 {
+		t.Errorf("Expected 50, got %f", val)
+	}
+}
+
+func TestElementPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{`#(type=="PushEvent").created_at`, `#(type=="PushEvent")`},
+		{`events.#(type=="PushEvent").payload.size`, `events.#(type=="PushEvent")`},
+		{`#(name=="a)b").id`, `#(name=="a)b")`},
+		{`#(labels.#(name=="bug")).number`, `#(labels.#(name=="bug"))`},
+		{`owner.login`, `owner`},
+		{`stargazers_count`, `@this`},
+	}
+	for _, tt := range tests {
+		if got := elementPath(tt.path); got != tt.expected {
+			t.Errorf("elementPath(%q): expected %q, got %q", tt.path, tt.expected, got)
+		}
+	}
+}
+
+func TestParseSamples_RelativeLabels(t *testing.T) {
+	events := `[
+		{"type": "WatchEvent", "created_at": "2024-01-02T00:00:00Z", "repo": {"name": "acme/lib"}},
+		{"type": "PushEvent", "created_at": "2024-01-01T00:00:00Z", "repo": {"name": "acme/app"}}
+	]`
+	req := config.RequestConfig{
+		ApiPath: "/users/octocat/events",
+		Metrics: []config.MetricConfig{{
+			Name:           "github_last_push_timestamp",
+			Path:           `#(type=="PushEvent").created_at`,
+			Help:           "Last push",
+			ValueType:      config.TypeDate,
+			Labels:         map[string]string{"repo": "repo.name"},
+			RelativeLabels: true,
+		}},
+	}
+	m := NewManager(&config.Config{Requests: []config.RequestConfig{req}})
+
+	samples := m.parseSamples(newJob(req, nil), events)
+	if len(samples) != 1 {
+		t.Fatalf("Expected 1 sample, got %d", len(samples))
+	}
+	if got := samples[0].labelValues; got[1] != "acme/app" {
+		t.Errorf("Expected repo acme/app, got %v", got)
+	}
+	if samples[0].value != 1704067200 {
+		t.Errorf("Expected 1704067200, got %f", samples[0].value)
+	}
+}
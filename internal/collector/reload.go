@@ -0,0 +1,233 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Reloadable serves metrics from the current Manager and replaces it when
+// the configuration is reloaded. A configuration that fails to load leaves
+// the running Manager untouched.
+type Reloadable struct {
+	load func() (*config.Config, error)
+	self *selfMetrics
+
+	mu      sync.Mutex // serializes reloads
+	ctx     context.Context
+	cancel  context.CancelFunc
+	standby bool
+	current atomic.Pointer[Manager]
+}
+
+// NewReloadable wraps a Manager built from cfg. load is called on every
+// reload to produce the next configuration.
+func NewReloadable(cfg *config.Config, load func() (*config.Config, error)) *Reloadable {
+	mgr := NewManager(cfg)
+	r := &Reloadable{load: load, self: mgr.self}
+	r.current.Store(mgr)
+	return r
+}
+
+// Start starts the current Manager. Managers installed by Reload are
+// started with the same parent context.
+func (r *Reloadable) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ctx = ctx
+	var mgrCtx context.Context
+	mgrCtx, r.cancel = context.WithCancel(ctx)
+	r.current.Load().Start(mgrCtx)
+}
+
+// Reload loads the configuration again and swaps in a new Manager.
+func (r *Reloadable) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.load()
+	if err != nil {
+		logger.Error("Config reload failed, keeping the current configuration", "err", err)
+		return err
+	}
+
+	old := r.current.Load()
+	if changed := restartOnly(old.cfg, cfg); len(changed) > 0 {
+		err := fmt.Errorf("%s cannot change on reload, restart the exporter", strings.Join(changed, ", "))
+		logger.Error("Config reload failed, keeping the current configuration", "err", err)
+		return err
+	}
+	mgr := NewManager(cfg)
+	mgr.self = r.self
+	if cfg.StateFile != "" && cfg.StateFile == old.cfg.StateFile {
+		// The state file may lag behind a fetch still running in old.
+		mgr.counters = old.counters
+	}
+	mgr.standby.Store(r.standby)
+	r.self.setConfigHash(cfg.Hash)
+	if r.ctx != nil {
+		var mgrCtx context.Context
+		cancel := r.cancel
+		mgrCtx, r.cancel = context.WithCancel(r.ctx)
+		mgr.Start(mgrCtx)
+		cancel()
+	}
+	r.current.Store(mgr)
+	r.forgetRemoved(old, mgr)
+	logRetired(old, mgr)
+
+	logger.Info("Config reloaded", "requests", len(cfg.Requests))
+	return nil
+}
+
+// forgetRemoved drops the self-metrics of requests the new configuration
+// no longer declares.
+func (r *Reloadable) forgetRemoved(old, current *Manager) {
+	kept := make(map[string]bool)
+	for _, j := range current.allJobs() {
+		kept[j.req.ApiPath] = true
+	}
+	for _, j := range old.allJobs() {
+		if !kept[j.req.ApiPath] {
+			r.self.forget(j.req)
+		}
+	}
+}
+
+// logRetired logs the metrics the new configuration no longer declares,
+// with the number of series they stop exporting.
+func logRetired(old, current *Manager) {
+	series := make(map[string]int)
+	for _, j := range old.allJobs() {
+		for _, s := range old.cached(j) {
+			series[s.info.Config.Name]++
+		}
+	}
+	for name := range old.metrics {
+		if _, kept := current.metrics[name]; !kept {
+			logger.Info("Metric removed by the reload, its series are no longer exported", "name", name, "series", series[name])
+		}
+	}
+}
+
+// SetStandby stops or resumes fetching from GitHub, for the current
+// Manager and those installed by later reloads.
+func (r *Reloadable) SetStandby(standby bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.standby = standby
+	r.current.Load().SetStandby(standby)
+}
+
+// SaveState writes the counters of the current Manager to the state file.
+func (r *Reloadable) SaveState() {
+	r.current.Load().SaveState()
+}
+
+// Manager returns the Manager currently serving metrics.
+func (r *Reloadable) Manager() *Manager {
+	return r.current.Load()
+}
+
+// SelfMetrics returns the request self-metrics, shared by every Manager.
+func (r *Reloadable) SelfMetrics() prometheus.Collector {
+	return r.self
+}
+
+// ConfigHash returns the hash of the configuration currently serving
+// metrics.
+func (r *Reloadable) ConfigHash() string {
+	return r.self.ConfigHash()
+}
+
+// StatusHandler returns the handler of the admin status API, shared by every
+// Manager.
+func (r *Reloadable) StatusHandler() http.Handler {
+	return r.self
+}
+
+// ReadyHandler serves /readyz: 200 once a configuration is loaded and an
+// API call succeeded with a token GitHub accepts, 503 otherwise. A
+// configuration without requests is ready right away.
+func (r *Reloadable) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(r.current.Load().allJobs()) > 0 {
+			if err := r.self.ready(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		_, _ = io.WriteString(w, "ok\n")
+	})
+}
+
+// GroupHandler serves /metrics/{group}: the series of the requests in the
+// group only, so that Prometheus jobs with different scrape intervals can
+// pull different subsets. Unknown groups answer 404.
+func (r *Reloadable) GroupHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mgr := r.current.Load()
+		name := req.PathValue("group")
+		if !mgr.hasGroup(name) {
+			http.NotFound(w, req)
+			return
+		}
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(groupCollector{mgr: mgr, group: name})
+		promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, req)
+	})
+}
+
+// groupCollector is the unchecked collector of one group of requests.
+type groupCollector struct {
+	mgr   *Manager
+	group string
+}
+
+func (g groupCollector) Describe(chan<- *prometheus.Desc) {}
+
+func (g groupCollector) Collect(ch chan<- prometheus.Metric) {
+	g.mgr.CollectGroup(g.group, ch)
+}
+
+// ServeProbe runs probes with the current configuration.
+func (r *Reloadable) ServeProbe(w http.ResponseWriter, req *http.Request) {
+	r.current.Load().ServeProbe(w, req)
+}
+
+// Describe sends no descriptors, which makes the Reloadable an unchecked
+// collector: the metrics of a reloaded configuration are exported without
+// registering again, and those it removes disappear from the next scrape.
+func (r *Reloadable) Describe(chan<- *prometheus.Desc) {}
+
+func (r *Reloadable) Collect(ch chan<- prometheus.Metric) {
+	r.current.Load().Collect(ch)
+}
+
+// restartOnly returns the sections of cfg that differ from old but are only
+// applied at startup: the webhook receiver, remote write and leader election
+// are wired up once by serve.
+func restartOnly(old, cfg *config.Config) []string {
+	var changed []string
+	if !reflect.DeepEqual(old.Webhook, cfg.Webhook) {
+		changed = append(changed, "webhook")
+	}
+	if !reflect.DeepEqual(old.RemoteWrite, cfg.RemoteWrite) {
+		changed = append(changed, "remote_write")
+	}
+	if !reflect.DeepEqual(old.LeaderElection, cfg.LeaderElection) {
+		changed = append(changed, "leader_election")
+	}
+	return changed
+}
@@ -0,0 +1,271 @@
+package collector
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func reloadTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 7, "public_repos": 3}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+}
+
+func TestReload_SwapsMetrics(t *testing.T) {
+	server := reloadTestServer(t)
+	defer server.Close()
+
+	before := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/a",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+	after := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/b",
+			Metrics: []config.MetricConfig{{Name: "github_public_repos", Path: "public_repos", Help: "Repos"}},
+		}},
+	}
+
+	r := NewReloadable(before, func() (*config.Config, error) { return after, nil })
+	if got := testutil.CollectAndCount(r, "github_followers"); got != 1 {
+		t.Errorf("Expected 1 github_followers series before reload, got %d", got)
+	}
+
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if got := testutil.CollectAndCount(r, "github_followers"); got != 0 {
+		t.Errorf("Expected github_followers to be gone after reload, got %d", got)
+	}
+	if got := testutil.CollectAndCount(r, "github_public_repos"); got != 1 {
+		t.Errorf("Expected 1 github_public_repos series after reload, got %d", got)
+	}
+	if got := testutil.CollectAndCount(r.SelfMetrics(), "github_exporter_request_success"); got != 1 {
+		t.Errorf("Expected self-metrics of the removed request to be forgotten, got %d series", got)
+	}
+}
+
+func TestReload_RegisteredOnce(t *testing.T) {
+	server := reloadTestServer(t)
+	defer server.Close()
+
+	before := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/a",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+	after := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/a",
+			Metrics: []config.MetricConfig{{Name: "github_public_repos", Path: "public_repos", Help: "Repos"}},
+		}},
+	}
+
+	r := NewReloadable(before, func() (*config.Config, error) { return after, nil })
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(r)
+	if _, err := reg.Gather(); err != nil {
+		t.Fatalf("Failed to gather before reload: %v", err)
+	}
+
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Expected the metrics added by the reload to be gathered, got %v", err)
+	}
+	names := make(map[string]bool)
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	if names["github_followers"] {
+		t.Error("Expected the removed github_followers to be gone right after the reload")
+	}
+	if !names["github_public_repos"] {
+		t.Error("Expected github_public_repos to be exported after the reload")
+	}
+}
+
+func TestReload_KeepsCurrentOnError(t *testing.T) {
+	server := reloadTestServer(t)
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/a",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+
+	r := NewReloadable(cfg, func() (*config.Config, error) { return nil, errors.New("broken config") })
+	current := r.Manager()
+
+	if err := r.Reload(); err == nil {
+		t.Fatal("Expected reload to fail")
+	}
+	if r.Manager() != current {
+		t.Error("Expected the current manager to be kept")
+	}
+	if got := testutil.CollectAndCount(r, "github_followers"); got != 1 {
+		t.Errorf("Expected 1 github_followers series, got %d", got)
+	}
+}
+
+func TestReload_RejectsRestartOnlyChanges(t *testing.T) {
+	server := reloadTestServer(t)
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Webhook:      config.WebhookConfig{Secret: "old", Path: "/webhook"},
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/a",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+	next := *cfg
+	next.Webhook.Secret = "new"
+	next.RemoteWrite.URL = "http://localhost:9090/api/v1/write"
+
+	r := NewReloadable(cfg, func() (*config.Config, error) { return &next, nil })
+	current := r.Manager()
+
+	err := r.Reload()
+	if err == nil {
+		t.Fatal("Expected reload to fail")
+	}
+	if !strings.Contains(err.Error(), "webhook, remote_write") {
+		t.Errorf("Expected the changed sections in the error, got %v", err)
+	}
+	if r.Manager() != current {
+		t.Error("Expected the current manager to be kept")
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusUnauthorized)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		if _, err := io.WriteString(w, `{"followers": 7}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/a",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+	r := NewReloadable(cfg, func() (*config.Config, error) { return cfg, nil })
+	ready := func() int {
+		rec := httptest.NewRecorder()
+		r.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before any API call, got %d", code)
+	}
+	testutil.CollectAndCount(r)
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with a rejected token, got %d", code)
+	}
+	status.Store(http.StatusOK)
+	testutil.CollectAndCount(r)
+	if code := ready(); code != http.StatusOK {
+		t.Errorf("Expected 200 after a successful call, got %d", code)
+	}
+}
+
+func TestReload_UpdatesConfigHash(t *testing.T) {
+	before := &config.Config{Hash: "aaa"}
+	after := &config.Config{Hash: "bbb"}
+
+	r := NewReloadable(before, func() (*config.Config, error) { return after, nil })
+	if got := r.ConfigHash(); got != "aaa" {
+		t.Errorf("Expected config hash aaa, got %q", got)
+	}
+
+	if err := r.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if got := r.ConfigHash(); got != "bbb" {
+		t.Errorf("Expected config hash bbb after reload, got %q", got)
+	}
+	rec := httptest.NewRecorder()
+	r.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	if !strings.Contains(rec.Body.String(), `"config_hash":"bbb"`) {
+		t.Errorf("Expected the status API to report the new hash, got %s", rec.Body.String())
+	}
+}
+
+func TestGroupHandler(t *testing.T) {
+	server := reloadTestServer(t)
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/users/a",
+				Group:   "social",
+				Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+			},
+			{
+				ApiPath: "/users/b",
+				Metrics: []config.MetricConfig{{Name: "github_public_repos", Path: "public_repos", Help: "Repos"}},
+			},
+		},
+	}
+	r := NewReloadable(cfg, nil)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics/{group}", r.GroupHandler())
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/social", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `github_followers{api_path="/users/a"} 7`) {
+		t.Errorf("Expected the series of the group, got:\n%s", body)
+	}
+	if strings.Contains(body, "github_public_repos") {
+		t.Errorf("Expected requests outside the group to be left out, got:\n%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/billing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown group, got %d", rec.Code)
+	}
+}
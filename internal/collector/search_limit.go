@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchLimiter spaces out calls to the search API, whose rate limit is a
+// separate pool of 30 requests per minute, and holds them back when GitHub
+// reports the pool exhausted.
+type searchLimiter struct {
+	interval time.Duration // between two calls
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next call
+}
+
+func newSearchLimiter(perMinute int) *searchLimiter {
+	return &searchLimiter{interval: time.Minute / time.Duration(perMinute)}
+}
+
+// wait blocks until a call may be made. Rather than sit on a concurrency
+// slot until ctx expires, it fails right away when the pool will not reset
+// before the deadline of ctx.
+func (l *searchLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	at := l.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	if deadline, ok := ctx.Deadline(); ok && at.After(deadline) {
+		l.mu.Unlock()
+		return fmt.Errorf("search rate limit exhausted until %s", at.Format(time.RFC3339))
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// update holds calls back until the reset time when the response reports
+// no remaining calls, or for as long as Retry-After asks after a secondary
+// rate limit.
+func (l *searchLimiter) update(header http.Header) {
+	var until time.Time
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			until = time.Unix(reset, 0)
+		}
+	}
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		if t := time.Now().Add(time.Duration(secs) * time.Second); t.After(until) {
+			until = t
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.next) {
+		l.next = until
+	}
+}
+
+// isSearch reports whether u is a search API call, e.g. /search/issues.
+func (m *Manager) isSearch(u *url.URL) bool {
+	base, err := url.Parse(m.cfg.GithubAPIURL)
+	if err != nil {
+		return false
+	}
+	if u.Host != base.Host {
+		return false
+	}
+	path := strings.TrimPrefix(u.Path, strings.TrimRight(base.Path, "/"))
+	return strings.HasPrefix(path, "/search/")
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestSearchLimiter_SpacesCalls(t *testing.T) {
+	l := newSearchLimiter(1200) // one call every 50ms
+
+	start := time.Now()
+	for range 3 {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("Failed to wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected 3 calls to take at least 100ms, took %s", elapsed)
+	}
+}
+
+func TestSearchLimiter_Exhausted(t *testing.T) {
+	l := newSearchLimiter(30)
+	header := http.Header{}
+	header.Set("X-RateLimit-Remaining", "0")
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+	l.update(header)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := l.wait(ctx); err == nil {
+		t.Error("Expected an error while the search pool is exhausted")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected to fail without waiting for the deadline, took %s", elapsed)
+	}
+}
+
+func TestSearchLimiter_RetryAfter(t *testing.T) {
+	l := newSearchLimiter(30)
+	header := http.Header{}
+	header.Set("Retry-After", "30")
+	l.update(header)
+
+	if until := time.Until(l.next); until < 29*time.Second {
+		t.Errorf("Expected calls to be held for 30s, got %s", until)
+	}
+}
+
+func TestFetch_SearchRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/search/issues" {
+			w.Header().Set("X-RateLimit-Resource", "search")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, `{"total_count": 3}`)
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL, Timeout: "1s"})
+	search := config.RequestConfig{ApiPath: "/search/issues?q=is:open", Timeout: "1s"}
+	if _, err := m.fetch(context.Background(), search); err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+	if _, err := m.fetch(context.Background(), search); err == nil {
+		t.Error("Expected the second search to fail until the pool resets")
+	}
+	if _, err := m.fetch(context.Background(), config.RequestConfig{ApiPath: "/repos/acme/app", Timeout: "1s"}); err != nil {
+		t.Errorf("Expected other endpoints not to be limited, got %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected 2 API calls, got %d", got)
+	}
+}
+
+func TestIsSearch(t *testing.T) {
+	m := &Manager{cfg: &config.Config{GithubAPIURL: "https://ghe.example.com/api/v3"}}
+	tests := map[string]bool{
+		"https://ghe.example.com/api/v3/search/issues?q=is:pr": true,
+		"https://ghe.example.com/api/v3/search/code":           true,
+		"https://ghe.example.com/api/v3/repos/acme/search":     false,
+	}
+	for raw, expected := range tests {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", raw, err)
+		}
+		if got := m.isSearch(u); got != expected {
+			t.Errorf("Expected isSearch(%s) to be %t, got %t", raw, expected, got)
+		}
+	}
+}
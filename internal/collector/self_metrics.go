@@ -0,0 +1,160 @@
+package collector
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// selfMetrics describes the exporter's own scrapes, so dashboards can tell
+// "GitHub returned zero" apart from "the request failed".
+type selfMetrics struct {
+	success  *prometheus.GaugeVec
+	duration *prometheus.GaugeVec
+	errors   *prometheus.CounterVec
+	oversize *prometheus.CounterVec
+	phases   *prometheus.HistogramVec // API call phases by host
+
+	mu         sync.Mutex
+	status     map[string]RequestStatus // by api_path, served by /api/status
+	configHash string                   // hash of the active configuration
+}
+
+func newSelfMetrics() *selfMetrics {
+	return &selfMetrics{
+		success: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "github_exporter_request_success",
+			Help: "Whether the last fetch of the request succeeded (1) or failed (0)",
+		}, []string{"api_path", "request"}),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "github_exporter_request_duration_seconds",
+			Help: "Duration of the last fetch of the request",
+		}, []string{"api_path", "request"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "github_exporter_request_errors_total",
+			Help: "Failed fetches of the request",
+		}, []string{"api_path", "request"}),
+		oversize: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "github_exporter_response_too_large_total",
+			Help: "Responses of the request dropped for exceeding max_response_bytes",
+		}, []string{"api_path", "request"}),
+		phases: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "github_exporter_http_phase_duration_seconds",
+			Help: "Duration of the phases of API calls: dns, connect, tls, and ttfb from the request being sent to the first response byte",
+		}, []string{"host", "phase"}),
+		status: make(map[string]RequestStatus),
+	}
+}
+
+func (s *selfMetrics) Describe(ch chan<- *prometheus.Desc) {
+	s.success.Describe(ch)
+	s.duration.Describe(ch)
+	s.errors.Describe(ch)
+	s.oversize.Describe(ch)
+	s.phases.Describe(ch)
+}
+
+func (s *selfMetrics) Collect(ch chan<- prometheus.Metric) {
+	s.success.Collect(ch)
+	s.duration.Collect(ch)
+	s.errors.Collect(ch)
+	s.oversize.Collect(ch)
+	s.phases.Collect(ch)
+}
+
+// observe records the outcome of a fetch of req. The request label holds
+// its name, empty for unnamed requests.
+func (s *selfMetrics) observe(req config.RequestConfig, took time.Duration, err error) {
+	s.mu.Lock()
+	s.status[req.ApiPath] = newRequestStatus(req, took, err)
+	s.mu.Unlock()
+
+	s.duration.WithLabelValues(req.ApiPath, req.Name).Set(took.Seconds())
+	if err != nil {
+		s.success.WithLabelValues(req.ApiPath, req.Name).Set(0)
+		s.errors.WithLabelValues(req.ApiPath, req.Name).Inc()
+		return
+	}
+	s.success.WithLabelValues(req.ApiPath, req.Name).Set(1)
+}
+
+// truncated counts a response of req larger than max_response_bytes.
+func (s *selfMetrics) truncated(req config.RequestConfig) {
+	s.oversize.WithLabelValues(req.ApiPath, req.Name).Inc()
+}
+
+// trace returns req instrumented to observe the duration of its DNS
+// lookup, connection, TLS handshake and time to first byte, so slow
+// fetches can be attributed to the network or to GitHub. Connections are
+// not reused, every call goes through all the phases.
+func (s *selfMetrics) trace(req *http.Request) *http.Request {
+	host := req.URL.Host
+	var (
+		mu                               sync.Mutex
+		dnsStart, connectStart, tlsStart time.Time
+		wrote                            time.Time
+	)
+	since := func(phase string, start *time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !start.IsZero() {
+			s.phases.WithLabelValues(host, phase).Observe(time.Since(*start).Seconds())
+			*start = time.Time{}
+		}
+	}
+	started := func(start *time.Time) {
+		mu.Lock()
+		*start = time.Now()
+		mu.Unlock()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { started(&dnsStart) },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err == nil {
+				since("dns", &dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) { started(&connectStart) },
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				since("connect", &connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { started(&tlsStart) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				since("tls", &tlsStart)
+			}
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			if info.Err == nil {
+				started(&wrote)
+			}
+		},
+		GotFirstResponseByte: func() { since("ttfb", &wrote) },
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// forget drops the series of a request that is no longer fetched.
+func (s *selfMetrics) forget(req config.RequestConfig) {
+	s.mu.Lock()
+	delete(s.status, req.ApiPath)
+	s.mu.Unlock()
+
+	s.success.DeleteLabelValues(req.ApiPath, req.Name)
+	s.duration.DeleteLabelValues(req.ApiPath, req.Name)
+	s.errors.DeleteLabelValues(req.ApiPath, req.Name)
+	s.oversize.DeleteLabelValues(req.ApiPath, req.Name)
+}
+
+// SelfMetrics returns the collector for the exporter's own request metrics.
+func (m *Manager) SelfMetrics() prometheus.Collector {
+	return m.self
+}
@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSelfMetrics_SuccessAndFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 0}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/users/test",
+				Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+			},
+			{
+				ApiPath: "/broken",
+				Metrics: []config.MetricConfig{{Name: "github_broken", Path: "value", Help: "Broken"}},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	for range 2 {
+		ch := make(chan prometheus.Metric, 10)
+		m.Collect(ch)
+		close(ch)
+	}
+
+	if got := testutil.ToFloat64(m.self.success.WithLabelValues("/users/test", "")); got != 1 {
+		t.Errorf("Expected success 1 for /users/test, got %f", got)
+	}
+	if got := testutil.ToFloat64(m.self.success.WithLabelValues("/broken", "")); got != 0 {
+		t.Errorf("Expected success 0 for /broken, got %f", got)
+	}
+	if got := testutil.ToFloat64(m.self.errors.WithLabelValues("/broken", "")); got != 2 {
+		t.Errorf("Expected 2 errors for /broken, got %f", got)
+	}
+	if got := testutil.CollectAndCount(m.SelfMetrics(), "github_exporter_request_duration_seconds"); got != 2 {
+		t.Errorf("Expected 2 duration series, got %d", got)
+	}
+}
+
+func TestSelfMetrics_Forget(t *testing.T) {
+	s := newSelfMetrics()
+	req := config.RequestConfig{Name: "gone", ApiPath: "/repos/acme/gone"}
+	s.observe(req, 0, nil)
+	s.forget(req)
+
+	if got := testutil.CollectAndCount(s); got != 0 {
+		t.Errorf("Expected no series after forget, got %d", got)
+	}
+}
+
+func TestSelfMetrics_RequestName(t *testing.T) {
+	s := newSelfMetrics()
+	s.observe(config.RequestConfig{Name: "app stars", ApiPath: "/repos/acme/app"}, 0, errors.New("boom"))
+
+	if got := testutil.ToFloat64(s.errors.WithLabelValues("/repos/acme/app", "app stars")); got != 1 {
+		t.Errorf("Expected 1 error for the named request, got %f", got)
+	}
+	if got := s.Status()[0].Name; got != "app stars" {
+		t.Errorf("Expected the name in the status, got %q", got)
+	}
+}
+
+func TestSelfMetrics_ResponseTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 12345}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL, MaxResponseBytes: 10})
+	req := config.RequestConfig{ApiPath: "/users/test"}
+	if _, err := m.fetch(context.Background(), req); err == nil {
+		t.Error("Expected an error for a response over max_response_bytes")
+	}
+	if got := testutil.ToFloat64(m.self.oversize.WithLabelValues("/users/test", "")); got != 1 {
+		t.Errorf("Expected 1 oversized response, got %v", got)
+	}
+
+	m = NewManager(&config.Config{GithubAPIURL: server.URL, MaxResponseBytes: int64(len(`{"followers": 12345}`))})
+	if _, err := m.fetch(context.Background(), req); err != nil {
+		t.Errorf("Expected a response of exactly max_response_bytes to be read, got %v", err)
+	}
+}
+
+func TestSelfMetrics_HTTPPhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, `{"followers": 1}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL, InsecureSkipVerify: true})
+	for range 2 {
+		if _, err := m.fetch(context.Background(), config.RequestConfig{ApiPath: "/users/test"}); err != nil {
+			t.Fatalf("Failed to fetch: %v", err)
+		}
+	}
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	for _, phase := range []string{"connect", "tls", "ttfb"} {
+		var metric dto.Metric
+		if err := m.self.phases.WithLabelValues(host, phase).(prometheus.Histogram).Write(&metric); err != nil {
+			t.Fatalf("Failed to read the %s histogram: %v", phase, err)
+		}
+		if got := metric.GetHistogram().GetSampleCount(); got != 2 {
+			t.Errorf("Expected 2 %s observations, got %d", phase, got)
+		}
+	}
+	// The server listens on an IP address, there is nothing to resolve.
+	if got := testutil.CollectAndCount(m.self.phases, "github_exporter_http_phase_duration_seconds"); got != 3 {
+		t.Errorf("Expected 3 phase series, got %d", got)
+	}
+}
@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"context"
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/eleboucher/github-exporter/internal/semver"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// dotcomVersion is reported when /meta carries no installed_version,
+// which is the case on github.com.
+const dotcomVersion = "dotcom"
+
+var serverVersionDesc = prometheus.NewDesc(
+	"github_server_version_info",
+	"GitHub server version detected at startup",
+	[]string{"version"},
+	nil,
+)
+
+// detectServerVersion queries /meta, which on GitHub Enterprise Server
+// reports the installed version.
+func (m *Manager) detectServerVersion(ctx context.Context) {
+	body, err := m.fetch(ctx, config.RequestConfig{ApiPath: "/meta"})
+	if err != nil {
+		logger.Warn("Could not detect GitHub server version, version-gated requests will still be fetched", "err", err)
+		return
+	}
+
+	version := gjson.GetBytes(body, "installed_version").String()
+	if version == "" {
+		version = dotcomVersion
+	}
+	logger.Info("Detected GitHub server version", "version", version)
+
+	m.mu.Lock()
+	m.serverVersion = version
+	m.mu.Unlock()
+}
+
+// supported reports whether the detected server is recent enough for req.
+// Unknown versions and github.com are always considered recent enough.
+func (m *Manager) supported(req config.RequestConfig) bool {
+	if req.MinServerVersion == "" {
+		return true
+	}
+
+	m.mu.RLock()
+	version := m.serverVersion
+	m.mu.RUnlock()
+	if version == "" || version == dotcomVersion {
+		return true
+	}
+
+	have, err := semver.Parse(version)
+	if err != nil {
+		return true
+	}
+	want, err := semver.Parse(req.MinServerVersion)
+	if err != nil {
+		return true
+	}
+	return have.Compare(want) >= 0
+}
+
+func (m *Manager) collectServerVersion(ch chan<- prometheus.Metric) {
+	m.mu.RLock()
+	version := m.serverVersion
+	m.mu.RUnlock()
+	if version == "" {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(serverVersionDesc, prometheus.GaugeValue, 1, version)
+}
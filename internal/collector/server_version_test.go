@@ -0,0 +1,105 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func newGHESServer(t *testing.T, meta string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		body := `{"value": 1}`
+		if r.URL.Path == "/meta" {
+			body = meta
+		}
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+}
+
+func TestCollect_SkipsRequestsForOlderServer(t *testing.T) {
+	server := newGHESServer(t, `{"installed_version": "3.9.2"}`)
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{
+				ApiPath: "/old",
+				Metrics: []config.MetricConfig{{Name: "github_old", Path: "value", Help: "Old"}},
+			},
+			{
+				ApiPath:          "/new",
+				MinServerVersion: "3.10",
+				Metrics:          []config.MetricConfig{{Name: "github_new", Path: "value", Help: "New"}},
+			},
+		},
+	}
+
+	m := NewManager(cfg)
+	m.detectServerVersion(context.Background())
+
+	ch := make(chan prometheus.Metric, 10)
+	m.Collect(ch)
+	close(ch)
+
+	names := make(map[string]bool)
+	var version string
+	for metric := range ch {
+		desc := metric.Desc().String()
+		switch desc {
+		case serverVersionDesc.String():
+			var metricDTO dto.Metric
+			if err := metric.Write(&metricDTO); err != nil {
+				t.Errorf("Failed to write metric: %v", err)
+			}
+			version = metricDTO.GetLabel()[0].GetValue()
+		case m.metrics["github_old"].Desc.String():
+			names["github_old"] = true
+		case m.metrics["github_new"].Desc.String():
+			names["github_new"] = true
+		}
+	}
+
+	if version != "3.9.2" {
+		t.Errorf("Expected version label '3.9.2', got '%s'", version)
+	}
+	if !names["github_old"] {
+		t.Error("Expected github_old to be collected")
+	}
+	if names["github_new"] {
+		t.Error("Expected github_new to be skipped on an older server")
+	}
+}
+
+func TestSupported_Dotcom(t *testing.T) {
+	server := newGHESServer(t, `{"verifiable_password_authentication": true}`)
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL})
+	m.detectServerVersion(context.Background())
+
+	if m.serverVersion != dotcomVersion {
+		t.Errorf("Expected version '%s', got '%s'", dotcomVersion, m.serverVersion)
+	}
+	if !m.supported(config.RequestConfig{MinServerVersion: "99.0"}) {
+		t.Error("Expected every request to be supported on github.com")
+	}
+}
+
+func TestSupported_UnknownVersion(t *testing.T) {
+	m := NewManager(&config.Config{GithubAPIURL: "http://127.0.0.1:0"})
+
+	if !m.supported(config.RequestConfig{MinServerVersion: "3.10"}) {
+		t.Error("Expected requests to be fetched when the version is unknown")
+	}
+}
@@ -0,0 +1,115 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateSaveInterval is how often the state file is written while the
+// exporter runs. Counters that changed since the last save are lost on a
+// crash, a clean shutdown saves them.
+const stateSaveInterval = time.Minute
+
+// stateVersion is bumped when the layout of the state file changes; files
+// of another version are ignored.
+const stateVersion = 1
+
+type stateFile struct {
+	Version int              `json:"version"`
+	Series  []*counterSeries `json:"series"`
+}
+
+// loadCounters returns the counters saved in path, or empty counters when
+// the file does not exist yet. Keys are rebuilt from the names and labels
+// since label values are not valid JSON keys once joined.
+func loadCounters(path string) (*counters, error) {
+	c := newCounters()
+	c.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return c, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if state.Version != stateVersion {
+		return c, fmt.Errorf("%s has version %d, expected %d", path, state.Version, stateVersion)
+	}
+	for _, s := range state.Series {
+		c.series[seriesKey(s.Name, s.Labels)] = s
+	}
+	return c, nil
+}
+
+// save writes the series to the state file when they changed since the
+// last save. The file is replaced atomically so a crash never leaves it
+// half written.
+func (c *counters) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	state := stateFile{Version: stateVersion, Series: make([]*counterSeries, 0, len(c.series))}
+	for _, s := range c.series {
+		state.Series = append(state.Series, s)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }() // already renamed on success
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// SaveState writes the counters to the state file, if one is configured
+// and they changed since the last save.
+func (m *Manager) SaveState() {
+	if err := m.counters.save(); err != nil {
+		logger.Error("Failed to save the state file", "state_file", m.cfg.StateFile, "err", err)
+	}
+}
+
+// runStateSaver saves the counters every stateSaveInterval, and a last
+// time when ctx is cancelled.
+func (m *Manager) runStateSaver(ctx context.Context) {
+	ticker := time.NewTicker(stateSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.SaveState()
+			return
+		case <-ticker.C:
+			m.SaveState()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestCounters_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	c, err := loadCounters(path)
+	if err != nil {
+		t.Fatalf("Expected a missing state file to be fine, got %v", err)
+	}
+	c.observe("github_downloads", []string{"acme/app"}, 10)
+	c.observe("github_downloads", []string{"acme/app"}, 15)
+	c.delta("github_stars_gained", []string{"acme/app"}, 40)
+	if err := c.save(); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	restarted, err := loadCounters(path)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if got := restarted.observe("github_downloads", []string{"acme/app"}, 12); got != 15 {
+		t.Errorf("Expected the counter to survive the restart at 15, got %f", got)
+	}
+	if got := restarted.delta("github_stars_gained", []string{"acme/app"}, 42); got != 2 {
+		t.Errorf("Expected a delta of 2 across the restart, got %f", got)
+	}
+}
+
+func TestLoadCounters_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0600); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	c, err := loadCounters(path)
+	if err == nil {
+		t.Error("Expected an error for an unknown state version")
+	}
+	if c == nil || len(c.series) != 0 {
+		t.Error("Expected empty counters to start over with")
+	}
+}
+
+func TestRunStateSaver_SavesOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	m := NewManager(&config.Config{StateFile: path})
+	m.counters.observe("github_downloads", []string{"acme/app"}, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.runStateSaver(ctx)
+
+	restarted, err := loadCounters(path)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	s, ok := restarted.series[seriesKey("github_downloads", []string{"acme/app"})]
+	if !ok || s.Total != 10 {
+		t.Errorf("Expected the counter saved on shutdown at 10, got %+v", s)
+	}
+}
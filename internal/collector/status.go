@@ -0,0 +1,135 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// requestError annotates a failed API call with the correlation ID sent in
+// its request ID header, so it can be found in proxy logs or quoted to
+// GitHub support.
+type requestError struct {
+	id  string
+	err error
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("%v (request id %s)", e.err, e.id)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// httpStatusError is a non-2xx answer from the API.
+type httpStatusError struct {
+	code int
+	err  error
+}
+
+func (e *httpStatusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpStatusError) Unwrap() error {
+	return e.err
+}
+
+// RequestStatus is the outcome of the last fetch of a request.
+type RequestStatus struct {
+	APIPath   string    `json:"api_path"`
+	Name      string    `json:"name,omitempty"`
+	Success   bool      `json:"success"`
+	LastFetch time.Time `json:"last_fetch"`
+	Duration  float64   `json:"duration_seconds"`
+	Error     string    `json:"error,omitempty"`
+	Status    int       `json:"status_code,omitempty"` // HTTP status of the failed call, if GitHub answered
+	RequestID string    `json:"request_id,omitempty"`  // correlation ID of the failed call
+}
+
+func newRequestStatus(req config.RequestConfig, took time.Duration, err error) RequestStatus {
+	st := RequestStatus{
+		APIPath:   req.ApiPath,
+		Name:      req.Name,
+		Success:   err == nil,
+		LastFetch: time.Now(),
+		Duration:  took.Seconds(),
+	}
+	if err != nil {
+		st.Error = err.Error()
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			st.RequestID = reqErr.id
+		}
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) {
+			st.Status = statusErr.code
+		}
+	}
+	return st
+}
+
+// Status returns the last fetch outcome of every request, by api_path.
+func (s *selfMetrics) Status() []RequestStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]RequestStatus, 0, len(s.status))
+	for _, st := range s.status {
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].APIPath < statuses[j].APIPath })
+	return statuses
+}
+
+func (s *selfMetrics) setConfigHash(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configHash = hash
+}
+
+// ConfigHash returns the hash of the active configuration.
+func (s *selfMetrics) ConfigHash() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.configHash
+}
+
+// ready reports why the exporter should not receive traffic yet: no API
+// call has succeeded so far, or GitHub rejects the token.
+func (s *selfMetrics) ready() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	succeeded := false
+	for _, st := range s.status {
+		if st.Status == http.StatusUnauthorized {
+			return fmt.Errorf("token rejected by GitHub for %s", st.APIPath)
+		}
+		succeeded = succeeded || st.Success
+	}
+	if !succeeded {
+		return errors.New("no successful GitHub API call yet")
+	}
+	return nil
+}
+
+// ServeHTTP serves the request statuses as JSON on /api/status.
+func (s *selfMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	body := map[string]any{"config_hash": s.ConfigHash(), "requests": s.Status()}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// StatusHandler returns the handler of the admin status API.
+func (m *Manager) StatusHandler() http.Handler {
+	return m.self
+}
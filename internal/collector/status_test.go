@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStatus_RecordsRequestIDOfFailedFetch(t *testing.T) {
+	var seen atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen.Store(r.Header.Get("X-Correlation-Id"))
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		GithubAPIURL:    server.URL,
+		RequestIDHeader: "X-Correlation-Id",
+		Requests: []config.RequestConfig{{
+			ApiPath: "/users/test",
+			Metrics: []config.MetricConfig{{Name: "github_followers", Path: "followers", Help: "Followers"}},
+		}},
+	}
+	m := NewManager(cfg)
+	ch := make(chan prometheus.Metric, 10)
+	m.Collect(ch)
+	close(ch)
+
+	id, _ := seen.Load().(string)
+	if id == "" {
+		t.Fatal("Expected a correlation ID header on the API call")
+	}
+
+	rec := httptest.NewRecorder()
+	m.StatusHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+
+	var body struct {
+		Requests []RequestStatus `json:"requests"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	if len(body.Requests) != 1 {
+		t.Fatalf("Expected 1 request status, got %d", len(body.Requests))
+	}
+	st := body.Requests[0]
+	if st.Success {
+		t.Error("Expected the request to be reported as failed")
+	}
+	if st.RequestID != id {
+		t.Errorf("Expected request id %q, got %q", id, st.RequestID)
+	}
+	if !strings.Contains(st.Error, id) {
+		t.Errorf("Expected the error to mention the request id, got %q", st.Error)
+	}
+}
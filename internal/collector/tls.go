@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// tlsConfig returns the TLS settings for API requests: the system roots
+// (which honour SSL_CERT_FILE and SSL_CERT_DIR) plus the certificates of
+// ca_bundle, typically a corporate proxy CA, and the client certificate
+// some GHES appliances require. It returns nil when nothing is configured
+// so the transport keeps its defaults.
+func tlsConfig(cfg *config.Config) (*tls.Config, error) {
+	if cfg.CABundle == "" && cfg.ClientCert == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", cfg.CABundle)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// proxyFunc returns the proxy of API requests: proxy_url when set,
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY otherwise.
+func proxyFunc(proxyURL string) func(*http.Request) (*url.URL, error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		// Rejected when the config is loaded.
+		return http.ProxyFromEnvironment
+	}
+	return http.ProxyURL(u)
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestFetch_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 1}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	untrusted := NewManager(&config.Config{GithubAPIURL: server.URL})
+	if _, err := untrusted.fetch(context.Background(), config.RequestConfig{ApiPath: "/users/test"}); err == nil {
+		t.Error("Expected the self-signed certificate to be rejected without a CA bundle")
+	}
+
+	trusted := NewManager(&config.Config{GithubAPIURL: server.URL, CABundle: bundle})
+	if _, err := trusted.fetch(context.Background(), config.RequestConfig{ApiPath: "/users/test"}); err != nil {
+		t.Errorf("Expected the CA bundle to be trusted, got %v", err)
+	}
+}
+
+func TestTLSConfig_InvalidBundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	if _, err := tlsConfig(&config.Config{CABundle: bundle}); err == nil {
+		t.Error("Expected an error for a bundle without certificates")
+	}
+}
+
+func TestFetch_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 1}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: server.URL, InsecureSkipVerify: true})
+	if _, err := m.fetch(context.Background(), config.RequestConfig{ApiPath: "/users/test"}); err != nil {
+		t.Errorf("Expected the self-signed certificate to be accepted, got %v", err)
+	}
+}
+
+func TestFetch_ProxyURL(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.WriteString(w, `{"followers": 1}`); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer proxy.Close()
+
+	m := NewManager(&config.Config{GithubAPIURL: "http://github.example.com/api/v3", ProxyURL: proxy.URL})
+	if _, err := m.fetch(context.Background(), config.RequestConfig{ApiPath: "/users/test"}); err != nil {
+		t.Fatalf("Expected the request to go through the proxy, got %v", err)
+	}
+	if want := "http://github.example.com/api/v3/users/test"; proxied != want {
+		t.Errorf("Expected the proxy to receive %s, got %s", want, proxied)
+	}
+}
@@ -0,0 +1,55 @@
+package collector
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/logging"
+)
+
+// tokenFile reads github_token_file and reads it again whenever its
+// modification time or size changes, so that rotated Docker and Kubernetes
+// secrets are picked up without a restart. Kubernetes swaps a symlink when
+// it updates a secret, which os.Stat follows.
+type tokenFile struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   string
+}
+
+func newTokenFile(path string) *tokenFile {
+	return &tokenFile{path: path}
+}
+
+// get returns the current token. A file that can no longer be read keeps
+// the last token it held.
+func (f *tokenFile) get() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		logger.Warn("Failed to stat the token file, keeping the current token", "path", f.path, "err", err)
+		return f.token
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.token
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		logger.Warn("Failed to read the token file, keeping the current token", "path", f.path, "err", err)
+		return f.token
+	}
+	token := strings.TrimSpace(string(data))
+	logging.AddSecrets(token)
+	if f.token != "" && token != f.token {
+		logger.Info("Token file changed, using the new token", "path", f.path)
+	}
+	f.token, f.modTime, f.size = token, info.ModTime(), info.Size()
+	return f.token
+}
@@ -0,0 +1,39 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenFile_Reread(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("ghp_first\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	f := newTokenFile(path)
+	if got := f.get(); got != "ghp_first" {
+		t.Errorf("Expected ghp_first, got %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("ghp_second\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	// Filesystems with a coarse mtime would otherwise see the same time.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("Failed to touch token file: %v", err)
+	}
+	if got := f.get(); got != "ghp_second" {
+		t.Errorf("Expected the rotated token ghp_second, got %q", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove token file: %v", err)
+	}
+	if got := f.get(); got != "ghp_second" {
+		t.Errorf("Expected the last token to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// selectTopN drops the samples of top_n metrics that do not rank among the
+// N highest sort keys. Series of every request compete, e.g. all the
+// repositories of a discovery. The sort key is the sample value or, with
+// top_n_by, the value of another metric with the same labels (api_path
+// excepted).
+func (m *Manager) selectTopN(samples []sample) []sample {
+	ranked := make(map[string][]int) // metric name to sample indexes
+	for i, s := range samples {
+		if s.info.Config.TopN > 0 && s.histogram == nil {
+			ranked[s.info.Config.Name] = append(ranked[s.info.Config.Name], i)
+		}
+	}
+	if len(ranked) == 0 {
+		return samples
+	}
+
+	drop := make(map[int]bool)
+	for _, indexes := range ranked {
+		info := samples[indexes[0]].info
+		if len(indexes) <= info.Config.TopN {
+			continue
+		}
+
+		keys := make(map[int]float64, len(indexes))
+		if by := info.Config.TopNBy; by != "" {
+			labelKeys := withoutAPIPath(info.LabelKeys)
+			sums := sumByLabels(samples, by, labelKeys)
+			for _, i := range indexes {
+				keys[i] = math.Inf(-1)
+				values := labelValuesFor(samples[i], labelKeys)
+				if sum, ok := sums[strings.Join(values, "\xff")]; ok {
+					keys[i] = sum.value
+				}
+			}
+		} else {
+			for _, i := range indexes {
+				keys[i] = samples[i].value
+			}
+		}
+
+		sort.SliceStable(indexes, func(a, b int) bool {
+			ka, kb := keys[indexes[a]], keys[indexes[b]]
+			if ka != kb {
+				return ka > kb
+			}
+			return strings.Join(samples[indexes[a]].labelValues, ",") < strings.Join(samples[indexes[b]].labelValues, ",")
+		})
+		for _, i := range indexes[info.Config.TopN:] {
+			drop[i] = true
+		}
+	}
+
+	kept := make([]sample, 0, len(samples)-len(drop))
+	for i, s := range samples {
+		if !drop[i] {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestSelectTopN(t *testing.T) {
+	stars := &MetricInfo{
+		LabelKeys: []string{"api_path", "repo"},
+		Config:    config.MetricConfig{Name: "github_stars", TopN: 2},
+	}
+	samples := []sample{
+		{info: stars, labelValues: []string{"/repos/acme/a", "acme/a"}, value: 5},
+		{info: stars, labelValues: []string{"/repos/acme/b", "acme/b"}, value: 50},
+		{info: stars, labelValues: []string{"/repos/acme/c", "acme/c"}, value: 1},
+		{info: stars, labelValues: []string{"/repos/acme/d", "acme/d"}, value: 20},
+	}
+
+	kept := (&Manager{}).selectTopN(samples)
+	if len(kept) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(kept))
+	}
+	if kept[0].labelValues[1] != "acme/b" || kept[1].labelValues[1] != "acme/d" {
+		t.Errorf("Expected acme/b and acme/d, got %v and %v", kept[0].labelValues, kept[1].labelValues)
+	}
+}
+
+func TestSelectTopN_By(t *testing.T) {
+	stars := &MetricInfo{
+		LabelKeys: []string{"api_path", "repo"},
+		Config:    config.MetricConfig{Name: "github_stars"},
+	}
+	issues := &MetricInfo{
+		LabelKeys: []string{"api_path", "repo"},
+		Config:    config.MetricConfig{Name: "github_open_issues", TopN: 1, TopNBy: "github_stars"},
+	}
+	samples := []sample{
+		{info: stars, labelValues: []string{"/repos/acme/a", "acme/a"}, value: 100},
+		{info: stars, labelValues: []string{"/repos/acme/b", "acme/b"}, value: 10},
+		{info: issues, labelValues: []string{"/repos/acme/a/issues", "acme/a"}, value: 1},
+		{info: issues, labelValues: []string{"/repos/acme/b/issues", "acme/b"}, value: 30},
+	}
+
+	kept := (&Manager{}).selectTopN(samples)
+	if len(kept) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(kept))
+	}
+	last := kept[2]
+	if last.info != issues || last.labelValues[1] != "acme/a" {
+		t.Errorf("Expected the open issues of acme/a to be kept, got %v", last.labelValues)
+	}
+}
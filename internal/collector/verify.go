@@ -0,0 +1,68 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+// EndpointCheck is the outcome of fetching a configured api_path once.
+type EndpointCheck struct {
+	APIPath string
+	Status  int // HTTP status, 0 when GitHub could not be reached
+	Err     error
+}
+
+// VerifyEndpoints fetches every configured request once and reports how
+// GitHub answered, so wrong paths and missing permissions show up at deploy
+// time instead of as metrics that never appear. Discovery and probe
+// templates are not checked since they need a repository or a target.
+func (m *Manager) VerifyEndpoints() []EndpointCheck {
+	checks := make([]EndpointCheck, len(m.cfg.Requests))
+	var wg sync.WaitGroup
+	for i, req := range m.cfg.Requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.semaphore <- struct{}{}
+			defer func() { <-m.semaphore }()
+
+			check := EndpointCheck{APIPath: req.ApiPath}
+			req, err := expandTime(req, time.Now())
+			if err == nil {
+				var resp *response
+				if resp, err = m.fetchURL(context.Background(), req, m.requestURL(req)); err == nil {
+					check.Status = resp.status
+				}
+			}
+			var expected *expectedStatusError
+			var statusErr *httpStatusError
+			switch {
+			case errors.As(err, &expected):
+				check.Status = expected.code
+			case errors.As(err, &statusErr):
+				check.Status = statusErr.code
+				check.Err = err
+			case err != nil:
+				check.Status = 0
+				check.Err = err
+			}
+			checks[i] = check
+		}()
+	}
+	wg.Wait()
+	return checks
+}
+
+// Fetch fetches req once, walking its pages and fanning out like a scrape,
+// and returns the JSON its metric paths are evaluated on.
+func (m *Manager) Fetch(req config.RequestConfig) ([]byte, error) {
+	body, err := m.fetchBody(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBody(req, body)
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestVerifyEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/app":
+			w.WriteHeader(http.StatusOK)
+			if _, err := io.WriteString(w, `{"stargazers_count": 1}`); err != nil {
+				t.Errorf("Failed to write response: %v", err)
+			}
+		case "/user/following/octocat":
+			w.WriteHeader(http.StatusNoContent)
+		case "/orgs/acme/audit-log":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	metrics := []config.MetricConfig{{Name: "github_value", Path: "value", Help: "Value"}}
+	cfg := &config.Config{
+		GithubAPIURL: server.URL,
+		Requests: []config.RequestConfig{
+			{ApiPath: "/repos/acme/app", Metrics: metrics},
+			{ApiPath: "/user/following/octocat", Metrics: metrics},
+			{ApiPath: "/orgs/acme/audit-log", Metrics: metrics},
+			{ApiPath: "/repos/acme/typo", Metrics: metrics},
+		},
+	}
+
+	checks := NewManager(cfg).VerifyEndpoints()
+	expected := []int{http.StatusOK, http.StatusNoContent, http.StatusForbidden, http.StatusNotFound}
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %d", len(expected), len(checks))
+	}
+	for i, status := range expected {
+		if checks[i].Status != status {
+			t.Errorf("Expected status %d for %s, got %d", status, checks[i].APIPath, checks[i].Status)
+		}
+		if (checks[i].Err == nil) != (status < http.StatusBadRequest) {
+			t.Errorf("Unexpected error for %s: %v", checks[i].APIPath, checks[i].Err)
+		}
+	}
+}
+
+func TestFetch_DecodesResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, "name,stars\napp,3\n"); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	mgr := NewManager(&config.Config{GithubAPIURL: server.URL})
+	body, err := mgr.Fetch(config.RequestConfig{ApiPath: "/report.csv", ResponseFormat: config.FormatCSV})
+	if err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+	if got := string(body); got != `[{"name":"app","stars":"3"}]` {
+		t.Errorf("Expected the CSV rows as JSON, got %s", got)
+	}
+}
@@ -0,0 +1,121 @@
+package collector
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// tracked describes the _min and _max metrics derived from a tracked gauge.
+type tracked struct {
+	window time.Duration
+	min    *MetricInfo // nil unless min is tracked
+	max    *MetricInfo // nil unless max is tracked
+}
+
+// newTracked returns the derived metrics of a gauge with track set, or nil.
+// Their descriptors carry the metric_prefix.
+func newTracked(metric config.MetricConfig, prefix string, labelKeys []string, constLabels prometheus.Labels) *tracked {
+	if len(metric.Track) == 0 {
+		return nil
+	}
+	window, err := time.ParseDuration(metric.TrackWindow)
+	if err != nil || window <= 0 {
+		window, _ = time.ParseDuration(config.DefaultTrackWindow)
+	}
+	t := &tracked{window: window}
+	for _, stat := range metric.Track {
+		derived := metric
+		derived.Name = metric.Name + "_" + stat
+		derived.Help = metric.Help + " (" + stat + " over " + window.String() + ")"
+		derived.Track, derived.TopN = nil, 0
+		info := &MetricInfo{
+			Desc:      prometheus.NewDesc(prefix+derived.Name, derived.Help, labelKeys, constLabels),
+			LabelKeys: labelKeys,
+			Config:    derived,
+		}
+		switch stat {
+		case "min":
+			t.min = info
+		case "max":
+			t.max = info
+		}
+	}
+	return t
+}
+
+// windows keeps the values of tracked gauges over their window, so that a
+// spike between two Prometheus scrapes of a frequently polled request still
+// shows in the exposed _min and _max.
+type windows struct {
+	mu     sync.Mutex
+	series map[string]*windowSeries
+}
+
+type windowSeries struct {
+	window time.Duration
+	points []windowPoint // oldest first
+}
+
+type windowPoint struct {
+	at    time.Time
+	value float64
+}
+
+func newWindows() *windows {
+	return &windows{series: make(map[string]*windowSeries)}
+}
+
+// observe records the values of the tracked samples and returns samples
+// followed by the min and max of each tracked series over its window.
+// Series that were not observed within their window are forgotten.
+func (w *windows) observe(samples []sample, now time.Time) []sample {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	out := samples
+	for _, s := range samples {
+		t := s.info.track
+		if t == nil {
+			continue
+		}
+		key := s.info.Config.Name + "\xff" + strings.Join(s.labelValues, "\xff")
+		ws, ok := w.series[key]
+		if !ok {
+			ws = &windowSeries{window: t.window}
+			w.series[key] = ws
+		}
+		ws.points = append(ws.points, windowPoint{at: now, value: s.value})
+		ws.trim(now)
+
+		lo, hi := ws.points[0].value, ws.points[0].value
+		for _, p := range ws.points[1:] {
+			lo = min(lo, p.value)
+			hi = max(hi, p.value)
+		}
+		if t.min != nil {
+			out = append(out, sample{info: t.min, labelValues: s.labelValues, value: lo, fetchedAt: s.fetchedAt})
+		}
+		if t.max != nil {
+			out = append(out, sample{info: t.max, labelValues: s.labelValues, value: hi, fetchedAt: s.fetchedAt})
+		}
+	}
+	for key, ws := range w.series {
+		if ws.trim(now); len(ws.points) == 0 {
+			delete(w.series, key)
+		}
+	}
+	return out
+}
+
+// trim drops the points older than the window.
+func (ws *windowSeries) trim(now time.Time) {
+	i := 0
+	for i < len(ws.points) && now.Sub(ws.points[i].at) > ws.window {
+		i++
+	}
+	ws.points = ws.points[i:]
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/config"
+)
+
+func TestWindows_Observe(t *testing.T) {
+	metric := config.MetricConfig{Name: "github_runners_busy", Track: []string{"min", "max"}, TrackWindow: "5m"}
+	labelKeys := []string{"api_path"}
+	info := &MetricInfo{LabelKeys: labelKeys, Config: metric, track: newTracked(metric, "", labelKeys, nil)}
+	w := newWindows()
+	start := time.Now()
+
+	for _, tt := range []struct {
+		after    time.Duration
+		value    float64
+		min, max float64
+	}{
+		{0, 2, 2, 2},
+		{time.Minute, 9, 2, 9},
+		{2 * time.Minute, 4, 2, 9},
+		{6 * time.Minute, 5, 4, 9}, // the first value left the window
+		{8 * time.Minute, 3, 3, 5},
+	} {
+		samples := w.observe([]sample{{info: info, labelValues: []string{"/orgs/acme/actions/runners"}, value: tt.value}}, start.Add(tt.after))
+		if len(samples) != 3 {
+			t.Fatalf("Expected 3 samples, got %d", len(samples))
+		}
+		if samples[1].info.Config.Name != "github_runners_busy_min" || samples[1].value != tt.min {
+			t.Errorf("After %s: expected min %f, got %s %f", tt.after, tt.min, samples[1].info.Config.Name, samples[1].value)
+		}
+		if samples[2].info.Config.Name != "github_runners_busy_max" || samples[2].value != tt.max {
+			t.Errorf("After %s: expected max %f, got %s %f", tt.after, tt.max, samples[2].info.Config.Name, samples[2].value)
+		}
+	}
+}
+
+func TestWindows_ForgetsIdleSeries(t *testing.T) {
+	metric := config.MetricConfig{Name: "github_queued_jobs", Track: []string{"max"}, TrackWindow: "1m"}
+	info := &MetricInfo{Config: metric, track: newTracked(metric, "", nil, nil)}
+	untracked := &MetricInfo{Config: config.MetricConfig{Name: "github_stars"}}
+	w := newWindows()
+	start := time.Now()
+
+	w.observe([]sample{{info: info, value: 7}}, start)
+	samples := w.observe([]sample{{info: untracked, value: 1}}, start.Add(2*time.Minute))
+
+	if len(samples) != 1 {
+		t.Errorf("Expected the untracked sample only, got %d samples", len(samples))
+	}
+	if len(w.series) != 0 {
+		t.Errorf("Expected the idle series to be forgotten, got %d", len(w.series))
+	}
+}
@@ -2,50 +2,347 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/eleboucher/github-exporter/internal/expr"
+	"github.com/eleboucher/github-exporter/internal/schedule"
+	"github.com/eleboucher/github-exporter/internal/semver"
 	"gopkg.in/yaml.v3"
 )
 
 type (
 	AggregateType   string
+	EmptyPolicy     string
+	MissingPolicy   string
+	ResponseFormat  string
+	MetricType      string
 	MetricValueType string
+	PaginateType    string
 )
 
 const (
 	AggregateSum   AggregateType = "sum"
 	AggregateCount AggregateType = "count"
 	AggregateMax   AggregateType = "max"
+	AggregateMin   AggregateType = "min"
+	AggregateAvg   AggregateType = "avg"
+	AggregateFirst AggregateType = "first" // the first item, e.g. the latest one with sort_by
 
-	DefaultGitHubAPIURL = "https://api.github.com"
+	EmptySkip EmptyPolicy = "skip" // no series at all, like a missing value
+	EmptyZero EmptyPolicy = "zero" // every metric reports 0
 
-	TypeFloat MetricValueType = "float"
-	TypeDate  MetricValueType = "date" // Parse ISO8601/RFC3339 to Unix Timestamp
+	MissingZero    MissingPolicy = "zero"    // the metric reports 0
+	MissingSkip    MissingPolicy = "skip"    // no series
+	MissingDefault MissingPolicy = "default" // the metric reports its default
+
+	MetricGauge     MetricType = "gauge"
+	MetricCounter   MetricType = "counter"   // exposed with a _total suffix, only ever increases
+	MetricHistogram MetricType = "histogram" // buckets every value of an array
+	MetricDelta     MetricType = "delta"     // change since the previous fetch, exposed as a gauge
+
+	DefaultGitHubAPIURL             = "https://api.github.com"
+	DefaultDiscoveryRefreshInterval = "1h"
+	DefaultWebhookPath              = "/webhook"
+	DefaultRequestIDHeader          = "X-Request-Id"
+	DefaultErrorBodyLimit           = 4096
+	DefaultMaxResponseBytes         = 64 << 20
+	DefaultMaxConcurrent            = 5
+	DefaultSearchRateLimit          = 30
+	DefaultTimeout                  = 10 * time.Second
+	DefaultAcceptedRetries          = 2
+	DefaultAcceptedRetryDelay       = "2s"
+	DefaultLeaseName                = "github-exporter"
+	DefaultLeaseDuration            = "15s"
+	DefaultRetryPeriod              = "5s"
+	DefaultFanOutMaxItems           = 20
+	DefaultTrackWindow              = "5m"
+	DefaultTargetLabel              = "target"
+	DefaultRemoteWriteInterval      = "1m"
+	DefaultRemoteWriteRetries       = 3
+	DateFormatUnix                  = "unix"    // date_format of epoch seconds
+	DateFormatUnixMs                = "unix_ms" // date_format of epoch milliseconds
+
+	FormatJSON ResponseFormat = "json"
+	FormatText ResponseFormat = "text" // the body is read as a single string, e.g. with regex
+	FormatCSV  ResponseFormat = "csv"  // an array with one object per row, keyed by the header
+	FormatXML  ResponseFormat = "xml"  // elements become objects, attributes "-name" keys
+
+	PaginateLink  PaginateType = "link"  // follow the Link header, including cursor-based "after" links
+	PaginateSCIM  PaginateType = "scim"  // startIndex/count with totalResults
+	PaginateCount PaginateType = "count" // one item per page, the rel="last" page number is the item count
+
+	TypeFloat    MetricValueType = "float"
+	TypeDate     MetricValueType = "date"     // Parse ISO8601/RFC3339, or date_format, to Unix Timestamp
+	TypeBool     MetricValueType = "bool"     // true is 1, false is 0
+	TypeChecksum MetricValueType = "checksum" // hash of the raw value, changes whenever the content does
+	TypeSemver   MetricValueType = "semver"   // version tag to MAJOR*1e6 + MINOR*1e3 + PATCH
 )
 
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// groupNameRE keeps group names usable as a single URL path segment.
+var groupNameRE = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
 type MetricConfig struct {
-	Name      string            `yaml:"name"`
-	Path      string            `yaml:"path"`
-	Help      string            `yaml:"help"`
-	Aggregate AggregateType     `yaml:"aggregate"` // sum, count, max
-	Labels    map[string]string `yaml:"labels"`
-	ValueType MetricValueType   `yaml:"value_type"`
+	Name            string             `yaml:"name"`
+	Path            string             `yaml:"path"`
+	Regex           string             `yaml:"regex"` // the first capture group in the value at path is the value
+	Expr            string             `yaml:"expr"`  // arithmetic over JSON paths, evaluated on each item of the array at path if set
+	Help            string             `yaml:"help"`
+	Aggregate       AggregateType      `yaml:"aggregate"` // sum, count, max, min, avg, first
+	Labels          map[string]string  `yaml:"labels"`
+	ConstLabels     map[string]string  `yaml:"const_labels"` // fixed label values, added to every series of the metric
+	ValueType       MetricValueType    `yaml:"value_type"`
+	DateFormat      string             `yaml:"date_format"`       // Go layout, unix or unix_ms for value_type date, defaults to RFC3339
+	Scale           float64            `yaml:"scale"`             // multiplier of the parsed value, e.g. 1024 for kilobytes, 0 means 1
+	Offset          float64            `yaml:"offset"`            // added to the parsed value after scale
+	OnMissing       MissingPolicy      `yaml:"on_missing"`        // zero (default), skip or default when the path matches nothing
+	Default         *float64           `yaml:"default"`           // value exported with on_missing default
+	ValueMap        map[string]float64 `yaml:"value_map"`         // string value to number, e.g. a conclusion
+	ValueMapDefault float64            `yaml:"value_map_default"` // value of strings missing from value_map
+	MetricType      MetricType         `yaml:"metric_type"`       // gauge (default), counter, delta or histogram
+	Buckets         []float64          `yaml:"buckets"`           // histogram upper bounds, defaults to the Prometheus defaults
+	LabelAllow      map[string]string  `yaml:"label_allow"`       // label name to regexp, samples not matching are dropped
+	LabelDeny       map[string]string  `yaml:"label_deny"`        // label name to regexp, samples matching are dropped
+	SortBy          string             `yaml:"sort_by"`           // path within each array item, prefix with - for descending order
+	Limit           int                `yaml:"limit"`             // keep only the first N array items, after sort_by
+	GroupBy         string             `yaml:"group_by"`          // comma-separated paths within each array item, one series per distinct combination
+	GroupLabel      string             `yaml:"group_label"`       // comma-separated labels carrying the group_by values, default to the paths
+	TopN            int                `yaml:"top_n"`             // keep only the N series with the highest sort key across all requests
+	TopNBy          string             `yaml:"top_n_by"`          // metric providing the sort key, defaults to the metric itself
+	UnescapeHTML    bool               `yaml:"unescape_html"`     // decode HTML entities such as &amp; in label values
+	RelativeLabels  bool               `yaml:"relative_labels"`   // evaluate label paths on the array element matched by path
+	Timestamp       string             `yaml:"timestamp"`         // path to the RFC3339 time the value was observed at, resolved like labels
+	Track           []string           `yaml:"track"`             // min and/or max, also exposed as <name>_min and <name>_max over track_window
+	TrackWindow     string             `yaml:"track_window"`      // window of the tracked values, defaults to 5m
+}
+
+// LabelKeys returns the sorted label names of the series of the metric:
+// api_path, its labels, the labels the exporter adds (extraKeys, e.g. repo
+// for discovered requests) and its group labels.
+func (m MetricConfig) LabelKeys(extraKeys []string) []string {
+	labelKeys := []string{"api_path"}
+	for k := range m.Labels {
+		labelKeys = append(labelKeys, k)
+	}
+	for _, k := range extraKeys {
+		if _, ok := m.Labels[k]; !ok {
+			labelKeys = append(labelKeys, k)
+		}
+	}
+	if m.GroupBy != "" {
+		_, groupLabels := m.GroupKeys()
+		labelKeys = append(labelKeys, groupLabels...)
+	}
+	sort.Strings(labelKeys)
+	return labelKeys
+}
+
+// GroupKeys returns the group_by paths and the labels carrying their
+// values, in the same order.
+func (m MetricConfig) GroupKeys() (paths, labels []string) {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts
+	}
+	return split(m.GroupBy), split(m.GroupLabel)
+}
+
+// GraphQLPaginateConfig describes how to walk a GraphQL connection. The
+// request body must be JSON; the cursor is injected into its "variables".
+type GraphQLPaginateConfig struct {
+	CursorVariable string `yaml:"cursor_variable"` // variable receiving pageInfo.endCursor, e.g. "after"
+	PageInfoPath   string `yaml:"page_info_path"`  // e.g. data.viewer.repositories.pageInfo
+	NodesPath      string `yaml:"nodes_path"`      // dotted path, defaults to the "nodes" sibling of page_info_path
+	MaxPages       int    `yaml:"max_pages"`       // 0 means unlimited
+}
+
+// PaginateConfig describes how to walk a paginated REST endpoint. The items of
+// every page are concatenated before metric paths are evaluated.
+type PaginateConfig struct {
+	Type      PaginateType   `yaml:"type"`       // link (default), scim or count
+	ItemsPath string         `yaml:"items_path"` // dotted path to the items array, empty for top-level arrays
+	MaxPages  int            `yaml:"max_pages"`  // 0 means unlimited
+	Since     *PaginateSince `yaml:"since"`
+}
+
+// PaginateSince stops walking a list sorted newest first once a page ends
+// with an item whose RFC3339 Field is older than Duration, like the @since
+// modifier that usually counts the items.
+type PaginateSince struct {
+	Field    string `yaml:"field"`
+	Duration string `yaml:"duration"`
+}
+
+// FanOutConfig fetches a follow-up endpoint once per value found in the
+// response, e.g. the pending deployments of every waiting workflow run.
+// ApiPath references the value as {{ .Item }}. Metrics are evaluated on the
+// array of follow-up responses, in the order of the values.
+type FanOutConfig struct {
+	Path     string `yaml:"path"`      // GJSON path to the values, e.g. workflow_runs.#.id
+	ApiPath  string `yaml:"api_path"`  // follow-up request, a GET with the token of the request
+	MaxItems int    `yaml:"max_items"` // follow-up requests per fetch, defaults to 20
 }
 
 type RequestConfig struct {
-	ApiPath string         `yaml:"api_path"`
-	Method  string         `yaml:"method"`
-	Body    string         `yaml:"body"`
-	Metrics []MetricConfig `yaml:"metrics"`
+	Name             string                 `yaml:"name"`         // identifies the request in logs and self-metrics, expanded like api_path
+	ApiPath          string                 `yaml:"api_path"`     // path under the base URL, or a full URL
+	BaseURL          string                 `yaml:"base_url"`     // overrides github_api_url, e.g. for a GHES instance next to github.com
+	Instance         string                 `yaml:"instance"`     // name of an instance providing base_url and token
+	QueryParams      map[string]string      `yaml:"query_params"` // URL-encoded into the query string, override those of api_path
+	Method           string                 `yaml:"method"`
+	Body             string                 `yaml:"body"`
+	ResponseFormat   ResponseFormat         `yaml:"response_format"`      // json (default) or text
+	CompressBody     bool                   `yaml:"compress_body"`        // send the body gzip-compressed, for large GraphQL queries
+	Interval         string                 `yaml:"interval"`             // duration or cron expression, fetched in the background
+	StaleTTL         string                 `yaml:"stale_ttl"`            // keep serving the last values this long when fetches fail
+	Timeout          string                 `yaml:"timeout"`              // deadline of every API call of the request, e.g. 30s for heavy GraphQL queries
+	AcceptedRetries  int                    `yaml:"accepted_retries"`     // retries while GitHub answers 202 Accepted, defaults to 2, negative disables them
+	AcceptedDelay    string                 `yaml:"accepted_retry_delay"` // wait between those retries, defaults to 2s
+	MinServerVersion string                 `yaml:"min_server_version"`   // skipped on GHES instances older than this
+	Token            string                 `yaml:"token"`                // overrides github_token, e.g. for enterprise-only endpoints
+	Group            string                 `yaml:"group"`                // also served on /metrics/<group>, for scrape jobs pulling a subset
+	Paginate         *PaginateConfig        `yaml:"paginate"`
+	GraphQLPaginate  *GraphQLPaginateConfig `yaml:"graphql_paginate"`
+	FanOut           *FanOutConfig          `yaml:"fan_out"`
+	Freshness        bool                   `yaml:"freshness"`     // add a <name>_last_fetch_timestamp_seconds gauge per metric
+	OnEmpty          EmptyPolicy            `yaml:"on_empty"`      // skip (default) or zero, for 204 and empty responses
+	ExpectStatus     []int                  `yaml:"expect_status"` // accepted status codes, defaults to any 2xx
+	StatusValues     map[int]float64        `yaml:"status_values"` // value of every metric when GitHub answers a non-2xx expected status
+	Metrics          []MetricConfig         `yaml:"metrics"`
+
+	TargetLabels map[string]string `yaml:"-"` // label carrying the target of requests expanded from targets
+}
+
+// TargetsConfig expands its request templates once per value when the
+// config is loaded, instead of repeating a request for every repository,
+// user or organization. Templates reference the value as {{ .Target }}.
+type TargetsConfig struct {
+	Values   []string        `yaml:"values"`
+	Label    string          `yaml:"label"` // label carrying the value, defaults to target
+	Requests []RequestConfig `yaml:"requests"`
+}
+
+// DiscoveryConfig enumerates the repositories of an organization and expands
+// its request templates once per repository. Templates reference the
+// repository as {{ .Repo }} (owner/name).
+type DiscoveryConfig struct {
+	Org             string          `yaml:"org"`
+	RefreshInterval string          `yaml:"refresh_interval"` // how often repositories are re-enumerated
+	SkipArchived    bool            `yaml:"skip_archived"`
+	SkipForks       bool            `yaml:"skip_forks"`
+	Instance        string          `yaml:"instance"` // instance the org lives on, also used by its requests
+	Requests        []RequestConfig `yaml:"requests"`
+}
+
+// InstanceConfig is a GitHub instance other than github_api_url, e.g. a
+// GHES appliance scraped next to github.com, with its own token.
+type InstanceConfig struct {
+	APIURL string `yaml:"api_url"`
+	Token  string `yaml:"token"`
+}
+
+// ProbeConfig holds the request templates run by /probe?target=<login>.
+// Templates reference the target as {{ .Target }}.
+type ProbeConfig struct {
+	Requests []RequestConfig `yaml:"requests"`
+}
+
+// ComputedConfig derives a metric by dividing two metrics declared by any
+// request. Series are matched on their labels, api_path excepted.
+type ComputedConfig struct {
+	Name        string `yaml:"name"`
+	Help        string `yaml:"help"`
+	Numerator   string `yaml:"numerator"`   // metric name
+	Denominator string `yaml:"denominator"` // metric name, series equal to 0 are skipped
+}
+
+// WebhookConfig enables the /webhook endpoint when a secret is set.
+type WebhookConfig struct {
+	Secret string `env:"GITHUB_WEBHOOK_SECRET" yaml:"secret"`
+	Path   string `yaml:"path"`
+}
+
+// RemoteWriteConfig pushes the collected samples to a Prometheus
+// remote_write endpoint, for exporters nothing can scrape.
+type RemoteWriteConfig struct {
+	URL         string            `env:"REMOTE_WRITE_URL" yaml:"url"`
+	Interval    string            `yaml:"interval"`                             // how often samples are pushed, defaults to 1m
+	Username    string            `env:"REMOTE_WRITE_USERNAME" yaml:"username"` // basic auth
+	Password    string            `env:"REMOTE_WRITE_PASSWORD" yaml:"password"` // basic auth
+	BearerToken string            `env:"REMOTE_WRITE_BEARER_TOKEN" yaml:"bearer_token"`
+	Headers     map[string]string `yaml:"headers"`     // e.g. X-Scope-OrgID for a multi-tenant Mimir
+	MaxRetries  int               `yaml:"max_retries"` // retries of a failed push, defaults to 3
+}
+
+// LeaderElectionConfig makes replicas of an active/passive pair elect a
+// leader, the only one fetching from GitHub. Standbys serve the metrics
+// they cached while they last led.
+type LeaderElectionConfig struct {
+	Type          string `yaml:"type"`                    // kubernetes (a Lease) or file (flock)
+	Path          string `yaml:"path"`                    // lock file of the file type
+	LeaseName     string `yaml:"lease_name"`              // defaults to github-exporter
+	Namespace     string `yaml:"namespace"`               // defaults to the pod's namespace
+	Identity      string `env:"POD_NAME" yaml:"identity"` // defaults to the hostname
+	LeaseDuration string `yaml:"lease_duration"`          // how long a silent leader keeps the Lease, defaults to 15s
+	RetryPeriod   string `yaml:"retry_period"`            // how often the lock is tried or renewed, defaults to 5s
 }
 
 type Config struct {
-	GithubAPIURL string          `env:"GITHUB_API_URL" yaml:"github_api_url" `
-	Token        string          `env:"GITHUB_TOKEN" yaml:"github_token"`
-	Requests     []RequestConfig `yaml:"requests"`
+	GithubAPIURL       string                    `env:"GITHUB_API_URL" yaml:"github_api_url" `
+	Token              string                    `env:"GITHUB_TOKEN" yaml:"github_token"`
+	TokenFile          string                    `env:"GITHUB_TOKEN_FILE" yaml:"github_token_file"`               // file holding the token, read again when it changes
+	CABundle           string                    `env:"GITHUB_CA_BUNDLE" yaml:"ca_bundle"`                        // PEM file appended to the system roots
+	ProxyURL           string                    `env:"GITHUB_PROXY_URL" yaml:"proxy_url"`                        // proxy for API requests, defaults to HTTPS_PROXY and HTTP_PROXY
+	ClientCert         string                    `env:"GITHUB_CLIENT_CERT" yaml:"client_cert"`                    // PEM client certificate presented to the API
+	ClientKey          string                    `env:"GITHUB_CLIENT_KEY" yaml:"client_key"`                      // PEM key of client_cert
+	InsecureSkipVerify bool                      `env:"GITHUB_INSECURE_SKIP_VERIFY" yaml:"insecure_skip_verify"`  // accept any server certificate, for tests only
+	DisableCompression bool                      `env:"DISABLE_COMPRESSION" yaml:"disable_compression"`           // fetch responses uncompressed, e.g. for proxies mangling gzip
+	RequestIDHeader    string                    `env:"REQUEST_ID_HEADER" yaml:"request_id_header"`               // header carrying the correlation ID of each API call
+	StateFile          string                    `env:"STATE_FILE" yaml:"state_file"`                             // keeps counter and delta values across restarts
+	ErrorBodyLimit     int                       `env:"ERROR_BODY_LIMIT" yaml:"error_body_limit"`                 // bytes of error responses kept for logs, defaults to 4096
+	MaxResponseBytes   int64                     `env:"MAX_RESPONSE_BYTES" yaml:"max_response_bytes"`             // larger responses fail the fetch, defaults to 64 MiB
+	MaxConcurrent      int                       `env:"MAX_CONCURRENT_REQUESTS" yaml:"max_concurrent_requests"`   // requests fetched in parallel, defaults to 5
+	MaxPerHost         int                       `env:"MAX_CONNECTIONS_PER_HOST" yaml:"max_connections_per_host"` // connections to a single host, 0 means unlimited
+	SearchRateLimit    int                       `env:"SEARCH_RATE_LIMIT" yaml:"search_rate_limit"`               // search API calls per minute, defaults to 30
+	ScrapeInterval     string                    `env:"SCRAPE_INTERVAL" yaml:"scrape_interval"`                   // default interval for every request
+	StaleTTL           string                    `env:"STALE_TTL" yaml:"stale_ttl"`                               // default stale_ttl for every request
+	Timeout            string                    `env:"REQUEST_TIMEOUT" yaml:"timeout"`                           // default timeout for every request, 10s if unset
+	Public             bool                      `env:"PUBLIC_MODE" yaml:"public"`                                // token-less mode throttled to the anonymous rate limit
+	MetricPrefix       string                    `env:"METRIC_PREFIX" yaml:"metric_prefix"`                       // prepended to the name of every request and computed metric
+	Labels             map[string]string         `yaml:"labels"`                                                  // fixed labels added to every series, unless the metric has a label of the same name
+	Instances          map[string]InstanceConfig `yaml:"instances"`
+	Requests           []RequestConfig           `yaml:"requests"`
+	Presets            []PresetConfig            `yaml:"presets"`
+	Targets            []TargetsConfig           `yaml:"targets"`
+	Computed           []ComputedConfig          `yaml:"computed"`
+	Discovery          []DiscoveryConfig         `yaml:"discovery"`
+	Probe              ProbeConfig               `yaml:"probe"`
+	Webhook            WebhookConfig             `yaml:"webhook"`
+	LeaderElection     LeaderElectionConfig      `yaml:"leader_election"`
+	RemoteWrite        RemoteWriteConfig         `yaml:"remote_write"`
+
+	Hash string `yaml:"-"` // SHA-256 of the rendered config file, hex encoded
 }
 
 func getEnvMap(githubUser string) map[string]string {
@@ -62,23 +359,51 @@ func getEnvMap(githubUser string) map[string]string {
 	return items
 }
 
-func Load(path string, githubUser string) (*Config, error) {
+// Load renders and parses the config at path. With strict, keys that match
+// no setting, such as a misspelled value_type, are an error.
+func Load(path string, githubUser string, strict bool) (*Config, error) {
+	data, _, err := renderAll(path, githubUser, strict)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, true)
+}
+
+// render reads the config file at path and executes it as a template over
+// the environment.
+func render(path string, githubUser string) ([]byte, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
-	tmpl, err := template.New("config").Parse(string(data))
+	tmpl, err := template.New("config").Funcs(configFuncs).Funcs(DeferredTimeFuncs()).Parse(string(data))
 	if err != nil {
 		return nil, err
 	}
+	vars := getEnvMap(githubUser)
+	// Discovery, probe and fan_out templates are expanded per repository,
+	// target or item at runtime, keep them intact through the load-time
+	// rendering.
+	vars["Repo"] = "{{ .Repo }}"
+	vars["Target"] = "{{ .Target }}"
+	vars["Item"] = "{{ .Item }}"
+
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, getEnvMap(githubUser)); err != nil {
+	if err := tmpl.Execute(&buf, vars); err != nil {
 		return nil, err
 	}
+	return buf.Bytes(), nil
+}
+
+// parse decodes a rendered config, applies defaults and validates it.
+// Metric conflicts are only checked when conflicts is set.
+func parse(data []byte, conflicts bool) (*Config, error) {
 	var cfg Config
-	if err := yaml.Unmarshal(buf.Bytes(), &cfg); err != nil {
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	sum := sha256.Sum256(data)
+	cfg.Hash = hex.EncodeToString(sum[:])
 
 	if err := env.Parse(&cfg); err != nil {
 		return nil, err
@@ -88,5 +413,758 @@ func Load(path string, githubUser string) (*Config, error) {
 		cfg.GithubAPIURL = DefaultGitHubAPIURL
 	}
 	cfg.GithubAPIURL = strings.TrimRight(cfg.GithubAPIURL, "/")
+	if cfg.Webhook.Path == "" {
+		cfg.Webhook.Path = DefaultWebhookPath
+	}
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = DefaultRequestIDHeader
+	}
+	if cfg.ErrorBodyLimit == 0 {
+		cfg.ErrorBodyLimit = DefaultErrorBodyLimit
+	}
+	if cfg.MaxResponseBytes == 0 {
+		cfg.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	if cfg.MaxResponseBytes < 0 {
+		return nil, fmt.Errorf("max_response_bytes must be positive")
+	}
+	if cfg.MaxConcurrent == 0 {
+		cfg.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if cfg.MaxConcurrent < 0 || cfg.MaxPerHost < 0 {
+		return nil, fmt.Errorf("max_concurrent_requests and max_connections_per_host must be positive")
+	}
+	if err := cfg.LeaderElection.normalize(); err != nil {
+		return nil, fmt.Errorf("leader_election: %w", err)
+	}
+	if err := cfg.RemoteWrite.normalize(); err != nil {
+		return nil, fmt.Errorf("remote_write: %w", err)
+	}
+	if cfg.TokenFile != "" {
+		if cfg.Token != "" {
+			return nil, fmt.Errorf("github_token and github_token_file are mutually exclusive")
+		}
+		if _, err := os.ReadFile(cfg.TokenFile); err != nil {
+			return nil, fmt.Errorf("github_token_file: %w", err)
+		}
+	}
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("ca_bundle: %w", err)
+		}
+		if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_bundle: no certificate found in %s", cfg.CABundle)
+		}
+	}
+
+	if cfg.ProxyURL != "" {
+		u, err := url.Parse(cfg.ProxyURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return nil, fmt.Errorf("proxy_url: expected an http, https or socks5 URL, got %q", cfg.ProxyURL)
+		}
+	}
+	if (cfg.ClientCert == "") != (cfg.ClientKey == "") {
+		return nil, fmt.Errorf("client_cert and client_key must be set together")
+	}
+	if cfg.ClientCert != "" {
+		if _, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey); err != nil {
+			return nil, fmt.Errorf("client_cert: %w", err)
+		}
+	}
+
+	if cfg.MetricPrefix != "" && !metricNameRE.MatchString(cfg.MetricPrefix) {
+		return nil, fmt.Errorf("metric_prefix: invalid metric name prefix %q", cfg.MetricPrefix)
+	}
+	for name, inst := range cfg.Instances {
+		if !IsAbsoluteURL(inst.APIURL) {
+			return nil, fmt.Errorf("instance %q: api_url must be an http or https URL", name)
+		}
+		inst.APIURL = strings.TrimRight(inst.APIURL, "/")
+		cfg.Instances[name] = inst
+	}
+	for name := range cfg.Labels {
+		if !labelNameRE.MatchString(name) || name == "api_path" {
+			return nil, fmt.Errorf("labels: invalid label name %q", name)
+		}
+	}
+
+	for _, p := range cfg.Presets {
+		bundle, err := expandPreset(p)
+		if err != nil {
+			return nil, err
+		}
+		for i := range bundle.Requests {
+			if p.Group != "" {
+				bundle.Requests[i].Group = p.Group
+			}
+			if p.Instance != "" {
+				bundle.Requests[i].Instance = p.Instance
+			}
+		}
+		cfg.Requests = append(cfg.Requests, bundle.Requests...)
+		cfg.Computed = append(cfg.Computed, bundle.Computed...)
+	}
+
+	for i, t := range cfg.Targets {
+		reqs, err := t.expand()
+		if err != nil {
+			return nil, fmt.Errorf("targets %d: %w", i, err)
+		}
+		cfg.Requests = append(cfg.Requests, reqs...)
+	}
+
+	for i := range cfg.Requests {
+		if err := cfg.normalizeRequest(&cfg.Requests[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range cfg.Discovery {
+		d := &cfg.Discovery[i]
+		if d.Org == "" {
+			return nil, fmt.Errorf("discovery %d: org is required", i)
+		}
+		if d.RefreshInterval == "" {
+			d.RefreshInterval = DefaultDiscoveryRefreshInterval
+		}
+		if _, err := schedule.Parse(d.RefreshInterval); err != nil {
+			return nil, fmt.Errorf("discovery %q: %w", d.Org, err)
+		}
+		if _, ok := cfg.Instances[d.Instance]; d.Instance != "" && !ok {
+			return nil, fmt.Errorf("discovery %q: unknown instance %q", d.Org, d.Instance)
+		}
+		for j := range d.Requests {
+			if d.Requests[j].Instance == "" {
+				d.Requests[j].Instance = d.Instance
+			}
+			if err := cfg.normalizeRequest(&d.Requests[j]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := range cfg.Probe.Requests {
+		if err := cfg.normalizeRequest(&cfg.Probe.Requests[i]); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Public {
+		if err := cfg.applyPublic(); err != nil {
+			return nil, err
+		}
+	}
+	if conflicts {
+		if err := cfg.checkMetricConflicts(); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.normalizeComputed(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
+
+// allMetrics returns the metrics of every request, discovery templates
+// included.
+func (c *Config) allMetrics() []MetricConfig {
+	var metrics []MetricConfig
+	for _, req := range c.Requests {
+		metrics = append(metrics, req.Metrics...)
+	}
+	for _, d := range c.Discovery {
+		for _, req := range d.Requests {
+			metrics = append(metrics, req.Metrics...)
+		}
+	}
+	return metrics
+}
+
+// RedactedValue replaces secrets in Redacted configs.
+const RedactedValue = "<redacted>"
+
+// Redacted returns a copy of c with its tokens, passwords, secrets and
+// remote_write headers replaced by RedactedValue, e.g. to print it.
+func (c *Config) Redacted() *Config {
+	r := *c
+	redact := func(s *string) {
+		if *s != "" {
+			*s = RedactedValue
+		}
+	}
+	redactRequests := func(reqs []RequestConfig) []RequestConfig {
+		reqs = slices.Clone(reqs)
+		for i := range reqs {
+			redact(&reqs[i].Token)
+		}
+		return reqs
+	}
+
+	redact(&r.Token)
+	if u, err := url.Parse(r.ProxyURL); err == nil && u.User != nil {
+		r.ProxyURL = u.Redacted()
+	}
+	r.Instances = maps.Clone(r.Instances)
+	for name, inst := range r.Instances {
+		redact(&inst.Token)
+		r.Instances[name] = inst
+	}
+	r.Requests = redactRequests(r.Requests)
+	r.Probe.Requests = redactRequests(r.Probe.Requests)
+	r.Targets = slices.Clone(r.Targets)
+	for i := range r.Targets {
+		r.Targets[i].Requests = redactRequests(r.Targets[i].Requests)
+	}
+	r.Discovery = slices.Clone(r.Discovery)
+	for i := range r.Discovery {
+		r.Discovery[i].Requests = redactRequests(r.Discovery[i].Requests)
+	}
+	redact(&r.Webhook.Secret)
+	redact(&r.RemoteWrite.Password)
+	redact(&r.RemoteWrite.BearerToken)
+	// Headers usually carry credentials, e.g. an API key.
+	r.RemoteWrite.Headers = maps.Clone(r.RemoteWrite.Headers)
+	for k := range r.RemoteWrite.Headers {
+		r.RemoteWrite.Headers[k] = RedactedValue
+	}
+	return &r
+}
+
+// Secrets returns the tokens, passwords, secrets and remote_write header
+// values of c, e.g. to keep them out of logs.
+func (c *Config) Secrets() []string {
+	secrets := []string{c.Token, c.Webhook.Secret, c.RemoteWrite.Password, c.RemoteWrite.BearerToken}
+	for _, inst := range c.Instances {
+		secrets = append(secrets, inst.Token)
+	}
+	reqs := slices.Concat(c.Requests, c.Probe.Requests)
+	for _, t := range c.Targets {
+		reqs = append(reqs, t.Requests...)
+	}
+	for _, d := range c.Discovery {
+		reqs = append(reqs, d.Requests...)
+	}
+	for _, req := range reqs {
+		secrets = append(secrets, req.Token)
+	}
+	for _, v := range c.RemoteWrite.Headers {
+		secrets = append(secrets, v)
+	}
+	return slices.DeleteFunc(secrets, func(s string) bool { return s == "" })
+}
+
+// metricConflict returns what differs between two declarations of the same
+// metric name, with their sorted label names, or "" when both can be
+// exported together.
+func metricConflict(prev MetricConfig, prevKeys []string, metric MetricConfig, keys []string) string {
+	switch {
+	case prev.Help != metric.Help:
+		return "help"
+	case metricType(prev) != metricType(metric):
+		return "metric_type"
+	case !slices.Equal(prevKeys, keys):
+		return fmt.Sprintf("label set (%s instead of %s)", strings.Join(keys, ", "), strings.Join(prevKeys, ", "))
+	case !maps.Equal(prev.ConstLabels, metric.ConstLabels):
+		return "const_labels"
+	}
+	return ""
+}
+
+// checkMetricConflicts fails when requests declare the same metric name with
+// a different help, type or label set, since only one of the declarations
+// could be exported.
+func (c *Config) checkMetricConflicts() error {
+	type decl struct {
+		apiPath   string
+		metric    MetricConfig
+		labelKeys []string
+	}
+	seen := make(map[string]decl)
+	check := func(req RequestConfig, extraKeys []string) error {
+		for _, metric := range req.Metrics {
+			labelKeys := metric.LabelKeys(extraKeys)
+			prev, ok := seen[metric.Name]
+			if !ok {
+				seen[metric.Name] = decl{apiPath: req.ApiPath, metric: metric, labelKeys: labelKeys}
+				continue
+			}
+			conflict := metricConflict(prev.metric, prev.labelKeys, metric, labelKeys)
+			if conflict == "" {
+				continue
+			}
+			return fmt.Errorf("request %q: metric %q is already declared by request %q with a different %s", req.ApiPath, metric.Name, prev.apiPath, conflict)
+		}
+		return nil
+	}
+	for _, req := range c.Requests {
+		if err := check(req, slices.Collect(maps.Keys(req.TargetLabels))); err != nil {
+			return err
+		}
+	}
+	for _, d := range c.Discovery {
+		for _, req := range d.Requests {
+			if err := check(req, []string{"repo"}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expand renders the request templates of t once per value.
+func (t TargetsConfig) expand() ([]RequestConfig, error) {
+	label := t.Label
+	if label == "" {
+		label = DefaultTargetLabel
+	}
+	if !labelNameRE.MatchString(label) || label == "api_path" {
+		return nil, fmt.Errorf("invalid label name %q", label)
+	}
+
+	var reqs []RequestConfig
+	for _, value := range t.Values {
+		if value == "" {
+			return nil, fmt.Errorf("empty value")
+		}
+		data := map[string]string{
+			"Target": value,
+			"Repo":   "{{ .Repo }}",
+			"Item":   "{{ .Item }}",
+		}
+		for _, tmpl := range t.Requests {
+			req, err := ExpandRequest(tmpl, data, DeferredTimeFuncs())
+			if err != nil {
+				return nil, err
+			}
+			// The requests of every target are normalized apart.
+			req.Metrics = slices.Clone(tmpl.Metrics)
+			req.TargetLabels = map[string]string{label: value}
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs, nil
+}
+
+// ExpandRequest renders the templates in the api_path, body, name and
+// query_params of a request template with data and funcs, e.g. {{ .Repo }}
+// or {{ daysAgo 30 }}. The template is left unchanged.
+func ExpandRequest(tmpl RequestConfig, data map[string]string, funcs template.FuncMap) (RequestConfig, error) {
+	req := tmpl
+
+	render := func(text string) (string, error) {
+		t, err := template.New("request").Funcs(funcs).Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("parsing template %q: %w", text, err)
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("rendering template %q: %w", text, err)
+		}
+		return buf.String(), nil
+	}
+
+	for _, field := range []*string{&req.ApiPath, &req.Body, &req.Name} {
+		expanded, err := render(*field)
+		if err != nil {
+			return req, err
+		}
+		*field = expanded
+	}
+	if len(tmpl.QueryParams) > 0 {
+		// Copied so that expanding does not alter the template.
+		req.QueryParams = make(map[string]string, len(tmpl.QueryParams))
+		for k, v := range tmpl.QueryParams {
+			expanded, err := render(v)
+			if err != nil {
+				return req, err
+			}
+			req.QueryParams[k] = expanded
+		}
+	}
+	return req, nil
+}
+
+// metricType returns the type of metric, gauge when unset.
+func metricType(metric MetricConfig) MetricType {
+	if metric.MetricType == "" {
+		return MetricGauge
+	}
+	return metric.MetricType
+}
+
+// normalizeComputed checks that computed metrics and top_n_by reference
+// declared metrics and drops the duplicates a preset used more than once
+// produces.
+func (c *Config) normalizeComputed() error {
+	declared := make(map[string]bool)
+	for _, metric := range c.allMetrics() {
+		declared[metric.Name] = true
+	}
+	for _, metric := range c.allMetrics() {
+		if metric.TopN < 0 {
+			return fmt.Errorf("metric %q: top_n must be positive", metric.Name)
+		}
+		if metric.TopNBy != "" && !declared[metric.TopNBy] {
+			return fmt.Errorf("metric %q: unknown top_n_by metric %q", metric.Name, metric.TopNBy)
+		}
+	}
+
+	seen := make(map[string]ComputedConfig)
+	var computed []ComputedConfig
+	for _, cm := range c.Computed {
+		if cm.Name == "" || cm.Numerator == "" || cm.Denominator == "" {
+			return fmt.Errorf("computed metric %q: name, numerator and denominator are required", cm.Name)
+		}
+		for _, ref := range []string{cm.Numerator, cm.Denominator} {
+			if !declared[ref] {
+				return fmt.Errorf("computed metric %q: unknown metric %q", cm.Name, ref)
+			}
+		}
+		if prev, ok := seen[cm.Name]; ok {
+			if prev != cm {
+				return fmt.Errorf("computed metric %q is declared twice with different definitions", cm.Name)
+			}
+			continue
+		}
+		seen[cm.Name] = cm
+		computed = append(computed, cm)
+	}
+	c.Computed = computed
+	return nil
+}
+
+// normalize applies the defaults of an enabled leader election and
+// validates it.
+func (l *LeaderElectionConfig) normalize() error {
+	switch l.Type {
+	case "":
+		return nil
+	case "kubernetes":
+		if l.LeaseName == "" {
+			l.LeaseName = DefaultLeaseName
+		}
+	case "file":
+		if l.Path == "" {
+			return fmt.Errorf("path is required")
+		}
+	default:
+		return fmt.Errorf("unknown type %q, expected kubernetes or file", l.Type)
+	}
+	if l.Identity == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("identity: %w", err)
+		}
+		l.Identity = host
+	}
+	if l.LeaseDuration == "" {
+		l.LeaseDuration = DefaultLeaseDuration
+	}
+	if l.RetryPeriod == "" {
+		l.RetryPeriod = DefaultRetryPeriod
+	}
+	lease, err := time.ParseDuration(l.LeaseDuration)
+	if err != nil || lease < time.Second {
+		return fmt.Errorf("lease_duration must be at least 1s, got %q", l.LeaseDuration)
+	}
+	retry, err := time.ParseDuration(l.RetryPeriod)
+	if err != nil || retry <= 0 || retry >= lease {
+		return fmt.Errorf("retry_period must be positive and shorter than lease_duration, got %q", l.RetryPeriod)
+	}
+	return nil
+}
+
+func (r *RemoteWriteConfig) normalize() error {
+	if r.URL == "" {
+		return nil
+	}
+	if !IsAbsoluteURL(r.URL) {
+		return fmt.Errorf("url must be an http or https URL, got %q", r.URL)
+	}
+	if r.BearerToken != "" && (r.Username != "" || r.Password != "") {
+		return fmt.Errorf("bearer_token and basic auth are mutually exclusive")
+	}
+	if r.Interval == "" {
+		r.Interval = DefaultRemoteWriteInterval
+	}
+	if d, err := time.ParseDuration(r.Interval); err != nil || d <= 0 {
+		return fmt.Errorf("invalid interval %q", r.Interval)
+	}
+	if r.MaxRetries == 0 {
+		r.MaxRetries = DefaultRemoteWriteRetries
+	}
+	if r.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must be positive")
+	}
+	return nil
+}
+
+// IsAbsoluteURL reports whether an api_path is a full URL rather than a path
+// under the base URL.
+func IsAbsoluteURL(apiPath string) bool {
+	return strings.HasPrefix(apiPath, "https://") || strings.HasPrefix(apiPath, "http://")
+}
+
+// Request returns the configured request with apiPath, or a GET of apiPath
+// with the defaults of the config, e.g. to try a path before adding it.
+func (c *Config) Request(apiPath string) (RequestConfig, error) {
+	for _, req := range c.Requests {
+		if req.ApiPath == apiPath {
+			return req, nil
+		}
+	}
+	req := RequestConfig{ApiPath: apiPath}
+	if err := c.normalizeRequest(&req); err != nil {
+		return RequestConfig{}, err
+	}
+	return req, nil
+}
+
+// normalizeRequest applies defaults to req and validates it.
+func (c *Config) normalizeRequest(req *RequestConfig) error {
+	if req.Group != "" && !groupNameRE.MatchString(req.Group) {
+		return fmt.Errorf("request %q: group %q may only contain letters, digits, '_' and '-'", req.ApiPath, req.Group)
+	}
+	if req.Instance != "" {
+		inst, ok := c.Instances[req.Instance]
+		if !ok {
+			return fmt.Errorf("request %q: unknown instance %q", req.ApiPath, req.Instance)
+		}
+		if req.BaseURL != "" {
+			return fmt.Errorf("request %q: instance and base_url are mutually exclusive", req.ApiPath)
+		}
+		req.BaseURL = inst.APIURL
+		if req.Token == "" {
+			req.Token = inst.Token
+		}
+	}
+	if req.BaseURL != "" {
+		if IsAbsoluteURL(req.ApiPath) {
+			return fmt.Errorf("request %q: base_url cannot be used with a full URL api_path", req.ApiPath)
+		}
+		if !IsAbsoluteURL(req.BaseURL) {
+			return fmt.Errorf("request %q: base_url %q must be an http or https URL", req.ApiPath, req.BaseURL)
+		}
+		req.BaseURL = strings.TrimRight(req.BaseURL, "/")
+	}
+	if req.Interval == "" {
+		req.Interval = c.ScrapeInterval
+	}
+	if req.Interval != "" {
+		if _, err := schedule.Parse(req.Interval); err != nil {
+			return fmt.Errorf("request %q: %w", req.ApiPath, err)
+		}
+	}
+	if req.StaleTTL == "" {
+		req.StaleTTL = c.StaleTTL
+	}
+	if req.StaleTTL != "" {
+		if _, err := time.ParseDuration(req.StaleTTL); err != nil {
+			return fmt.Errorf("request %q: stale_ttl: %w", req.ApiPath, err)
+		}
+	}
+	if req.Timeout == "" {
+		req.Timeout = c.Timeout
+	}
+	if req.Timeout != "" {
+		if d, err := time.ParseDuration(req.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("request %q: timeout must be a positive duration, got %q", req.ApiPath, req.Timeout)
+		}
+	}
+	if req.AcceptedRetries == 0 {
+		req.AcceptedRetries = DefaultAcceptedRetries
+	}
+	if req.AcceptedDelay == "" {
+		req.AcceptedDelay = DefaultAcceptedRetryDelay
+	}
+	if d, err := time.ParseDuration(req.AcceptedDelay); err != nil || d < 0 {
+		return fmt.Errorf("request %q: accepted_retry_delay must be a duration, got %q", req.ApiPath, req.AcceptedDelay)
+	}
+	switch req.ResponseFormat {
+	case "":
+		req.ResponseFormat = FormatJSON
+	case FormatJSON:
+	case FormatText, FormatCSV, FormatXML:
+		if req.Paginate != nil || req.GraphQLPaginate != nil {
+			return fmt.Errorf("request %q: %s responses cannot be paginated", req.ApiPath, req.ResponseFormat)
+		}
+	default:
+		return fmt.Errorf("request %q: unknown response_format %q, expected json, text, csv or xml", req.ApiPath, req.ResponseFormat)
+	}
+	switch req.OnEmpty {
+	case "":
+		req.OnEmpty = EmptySkip
+	case EmptySkip, EmptyZero:
+	default:
+		return fmt.Errorf("request %q: unknown on_empty %q, expected skip or zero", req.ApiPath, req.OnEmpty)
+	}
+	for _, code := range req.ExpectStatus {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("request %q: invalid status code %d in expect_status", req.ApiPath, code)
+		}
+	}
+	for code := range req.StatusValues {
+		if !slices.Contains(req.ExpectStatus, code) {
+			return fmt.Errorf("request %q: status_values maps %d, which is not in expect_status", req.ApiPath, code)
+		}
+	}
+	if req.MinServerVersion != "" {
+		if _, err := semver.Parse(req.MinServerVersion); err != nil {
+			return fmt.Errorf("request %q: %w", req.ApiPath, err)
+		}
+	}
+	for i := range req.Metrics {
+		metric := &req.Metrics[i]
+		if metric.GroupBy != "" {
+			if metric.GroupLabel == "" {
+				paths, _ := metric.GroupKeys()
+				for i, p := range paths {
+					paths[i] = invalidLabelChars.ReplaceAllString(p, "_")
+				}
+				metric.GroupLabel = strings.Join(paths, ",")
+			}
+			paths, labels := metric.GroupKeys()
+			if len(labels) != len(paths) {
+				return fmt.Errorf("request %q: metric %q: group_label needs one label per group_by path", req.ApiPath, metric.Name)
+			}
+			for i, label := range labels {
+				if _, ok := metric.Labels[label]; ok || label == "api_path" || slices.Contains(labels[:i], label) {
+					return fmt.Errorf("request %q: metric %q: group label %q is already used", req.ApiPath, metric.Name, label)
+				}
+			}
+		}
+		if req.ResponseFormat == FormatText && metric.Path == "" && metric.Expr == "" {
+			metric.Path = "@this"
+		}
+		if metric.Regex != "" {
+			if _, err := regexp.Compile(metric.Regex); err != nil {
+				return fmt.Errorf("request %q: metric %q: regex: %w", req.ApiPath, metric.Name, err)
+			}
+		}
+		if metric.Expr != "" {
+			if metric.MetricType == MetricHistogram {
+				return fmt.Errorf("request %q: metric %q: histograms need a path, not an expr", req.ApiPath, metric.Name)
+			}
+			if _, err := expr.Parse(metric.Expr); err != nil {
+				return fmt.Errorf("request %q: metric %q: %w", req.ApiPath, metric.Name, err)
+			}
+		}
+		if metric.MetricType == MetricHistogram && (metric.Scale != 0 || metric.Offset != 0) {
+			return fmt.Errorf("request %q: metric %q: histograms do not support scale and offset, set the buckets in the unit of the values", req.ApiPath, metric.Name)
+		}
+		if metric.Limit < 0 {
+			return fmt.Errorf("request %q: metric %q: limit must be positive", req.ApiPath, metric.Name)
+		}
+		switch metric.MetricType {
+		case "", MetricGauge, MetricCounter, MetricDelta:
+		case MetricHistogram:
+			for i := 1; i < len(metric.Buckets); i++ {
+				if metric.Buckets[i] <= metric.Buckets[i-1] {
+					return fmt.Errorf("request %q: metric %q: buckets must be in increasing order", req.ApiPath, metric.Name)
+				}
+			}
+		default:
+			return fmt.Errorf("request %q: metric %q: unknown metric_type %q", req.ApiPath, metric.Name, metric.MetricType)
+		}
+		for name := range metric.ConstLabels {
+			_, groupLabels := metric.GroupKeys()
+			if _, ok := metric.Labels[name]; ok || !labelNameRE.MatchString(name) || name == "api_path" || slices.Contains(groupLabels, name) {
+				return fmt.Errorf("request %q: metric %q: invalid const label %q", req.ApiPath, metric.Name, name)
+			}
+		}
+		if metric.OnMissing == "" && metric.Default != nil {
+			metric.OnMissing = MissingDefault
+		}
+		if metric.OnMissing != "" && metric.OnMissing != MissingZero && metric.Expr != "" {
+			return fmt.Errorf("request %q: metric %q: on_missing does not apply to exprs", req.ApiPath, metric.Name)
+		}
+		switch metric.OnMissing {
+		case "", MissingZero, MissingSkip:
+		case MissingDefault:
+			if metric.Default == nil {
+				return fmt.Errorf("request %q: metric %q: on_missing default requires a default", req.ApiPath, metric.Name)
+			}
+			if metric.MetricType == MetricHistogram {
+				return fmt.Errorf("request %q: metric %q: histograms do not support a default", req.ApiPath, metric.Name)
+			}
+		default:
+			return fmt.Errorf("request %q: metric %q: unknown on_missing %q, expected zero, skip or default", req.ApiPath, metric.Name, metric.OnMissing)
+		}
+		if metric.DateFormat != "" && metric.ValueType != TypeDate {
+			return fmt.Errorf("request %q: metric %q: date_format requires value_type date", req.ApiPath, metric.Name)
+		}
+		if len(metric.Track) > 0 {
+			if metric.MetricType != "" && metric.MetricType != MetricGauge {
+				return fmt.Errorf("request %q: metric %q: only gauges can be tracked", req.ApiPath, metric.Name)
+			}
+			for _, stat := range metric.Track {
+				if stat != "min" && stat != "max" {
+					return fmt.Errorf("request %q: metric %q: unknown track %q, expected min or max", req.ApiPath, metric.Name, stat)
+				}
+			}
+			if metric.TrackWindow == "" {
+				metric.TrackWindow = DefaultTrackWindow
+			}
+			if d, err := time.ParseDuration(metric.TrackWindow); err != nil || d <= 0 {
+				return fmt.Errorf("request %q: metric %q: invalid track_window %q", req.ApiPath, metric.Name, metric.TrackWindow)
+			}
+		}
+		for _, filters := range []map[string]string{metric.LabelAllow, metric.LabelDeny} {
+			for label, expr := range filters {
+				if _, err := regexp.Compile(expr); err != nil {
+					return fmt.Errorf("request %q: metric %q: label filter on %q: %w", req.ApiPath, metric.Name, label, err)
+				}
+			}
+		}
+	}
+	if p := req.Paginate; p != nil {
+		switch p.Type {
+		case "":
+			p.Type = PaginateLink
+		case PaginateLink, PaginateSCIM, PaginateCount:
+		default:
+			return fmt.Errorf("request %q: unknown paginate type %q", req.ApiPath, p.Type)
+		}
+		if p.Type == PaginateSCIM && p.ItemsPath == "" {
+			p.ItemsPath = "Resources"
+		}
+		if since := p.Since; since != nil {
+			if p.Type != PaginateLink {
+				return fmt.Errorf("request %q: paginate since requires the link type", req.ApiPath)
+			}
+			if d, err := time.ParseDuration(since.Duration); since.Field == "" || err != nil || d <= 0 {
+				return fmt.Errorf("request %q: paginate since requires a field and a positive duration", req.ApiPath)
+			}
+		}
+		if req.GraphQLPaginate != nil {
+			return fmt.Errorf("request %q: paginate and graphql_paginate are mutually exclusive", req.ApiPath)
+		}
+	}
+	if p := req.GraphQLPaginate; p != nil {
+		if p.CursorVariable == "" || p.PageInfoPath == "" {
+			return fmt.Errorf("request %q: graphql_paginate requires cursor_variable and page_info_path", req.ApiPath)
+		}
+		if p.NodesPath == "" {
+			parent, _, _ := cutLast(p.PageInfoPath, ".")
+			p.NodesPath = strings.TrimPrefix(parent+".nodes", ".")
+		}
+	}
+	if f := req.FanOut; f != nil {
+		if f.Path == "" || f.ApiPath == "" {
+			return fmt.Errorf("request %q: fan_out requires path and api_path", req.ApiPath)
+		}
+		if f.MaxItems == 0 {
+			f.MaxItems = DefaultFanOutMaxItems
+		}
+		if f.MaxItems < 0 {
+			return fmt.Errorf("request %q: fan_out max_items must be positive", req.ApiPath)
+		}
+	}
+	return nil
+}
+
+// cutLast is strings.Cut around the last occurrence of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return "", s, false
+}
@@ -0,0 +1,12 @@
+package config
+
+import (
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// configFuncs are the functions of the config template: the sprig function
+// set, e.g. {{ .GITHUB_ORGS | splitList "," }}. The time functions of
+// DeferredTimeFuncs take precedence over sprig's now and ago.
+var configFuncs template.FuncMap = sprig.TxtFuncMap()
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_TemplateFuncs(t *testing.T) {
+	t.Setenv("GITHUB_ORGS", "acme,Globex")
+	content := `
+github_api_url: '{{ .GITHUB_API_HOST | default "https://api.github.com" }}'
+requests:
+{{- range .GITHUB_ORGS | split "," }}
+  - api_path: "/orgs/{{ . | lower }}"
+    name: "{{ . | upper }}"
+{{- end }}
+  - api_path: '/search/issues?q={{ .GITHUB_ORGS | splitList "," | join "+org:" }}'
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.GithubAPIURL != "https://api.github.com" {
+		t.Errorf("Expected the default API URL, got %s", cfg.GithubAPIURL)
+	}
+	if len(cfg.Requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(cfg.Requests))
+	}
+	for i, want := range []string{"/orgs/acme", "/orgs/globex", "/search/issues?q=acme+org:Globex"} {
+		if got := cfg.Requests[i].ApiPath; got != want {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+	if got := cfg.Requests[1].Name; got != "GLOBEX" {
+		t.Errorf("Expected GLOBEX, got %s", got)
+	}
+}
+
+func TestLoad_TemplateRequired(t *testing.T) {
+	content := `github_token: '{{ .UNSET_TOKEN_VARIABLE | required "UNSET_TOKEN_VARIABLE must be set" }}'` + "\n"
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(configPath, "", true); err == nil {
+		t.Error("Expected an error for a required variable")
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configDoc is a rendered config file.
+type configDoc struct {
+	path string
+	data []byte
+	root *yaml.Node // top-level mapping, nil for an empty file
+}
+
+// renderAll renders the config at path and the files it includes, and
+// merges them into a single document. path may be a file, a directory
+// whose *.yaml and *.yml files are merged, or a glob. Lists are
+// concatenated and mappings merged, a setting given by two files is an
+// error. A single file without include is returned as rendered. With
+// strict, keys matching no setting are an error.
+func renderAll(path string, githubUser string, strict bool) ([]byte, []configDoc, error) {
+	files, err := configFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var docs []configDoc
+	for _, file := range files {
+		doc, err := renderDoc(file, githubUser)
+		if err != nil {
+			return nil, nil, err
+		}
+		includes, err := includedFiles(doc)
+		if err != nil {
+			return nil, nil, err
+		}
+		docs = append(docs, doc)
+		for _, inc := range includes {
+			frag, err := renderDoc(inc, githubUser)
+			if err != nil {
+				return nil, nil, err
+			}
+			if mappingValue(frag.root, "include") != nil {
+				return nil, nil, fmt.Errorf("%s: included files cannot include other files", inc)
+			}
+			docs = append(docs, frag)
+		}
+	}
+	if strict {
+		if err := checkFields(docs); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(docs) == 1 && mappingValue(docs[0].root, "include") == nil {
+		return docs[0].data, docs, nil
+	}
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, doc := range docs {
+		if doc.root == nil {
+			continue
+		}
+		if err := mergeMapping(merged, doc.root, ""); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", doc.path, err)
+		}
+	}
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, docs, nil
+}
+
+// configFiles returns the files path designates, sorted.
+func configFiles(path string) ([]string, error) {
+	pattern := path
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		pattern = filepath.Join(path, "*.y*ml")
+	} else if !strings.ContainsAny(path, "*?[") {
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	var files []string
+	for _, m := range matches {
+		if ext := filepath.Ext(m); ext == ".yaml" || ext == ".yml" {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("config %s: no config file found", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func renderDoc(path string, githubUser string) (configDoc, error) {
+	data, err := render(path, githubUser)
+	if err != nil {
+		return configDoc{}, fmt.Errorf("%s: %w", path, err)
+	}
+	doc := configDoc{path: path, data: data}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return doc, fmt.Errorf("%s: %w", path, err)
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		doc.root = node.Content[0]
+		if doc.root.Kind != yaml.MappingNode {
+			return doc, fmt.Errorf("%s: expected a mapping at the top level", path)
+		}
+	}
+	return doc, nil
+}
+
+// includedFiles returns the files matched by the include globs of doc,
+// relative to the directory of doc, and removes them from doc.
+func includedFiles(doc configDoc) ([]string, error) {
+	n := mappingValue(doc.root, "include")
+	if n == nil {
+		return nil, nil
+	}
+	patterns := []*yaml.Node{n}
+	if n.Kind == yaml.SequenceNode {
+		patterns = n.Content
+	}
+
+	var files []string
+	for _, p := range patterns {
+		pattern := p.Value
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(doc.path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: include %q: %w", doc.path, p.Line, p.Value, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(p.Value, "*?[") {
+			return nil, fmt.Errorf("%s:%d: include %q: no such file", doc.path, p.Line, p.Value)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// mergeMapping merges the mapping src into dst. key is the path of dst, for
+// errors.
+func mergeMapping(dst, src *yaml.Node, key string) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		k, v := src.Content[i], src.Content[i+1]
+		name := k.Value
+		if key != "" {
+			name = key + "." + k.Value
+		}
+		if name == "include" {
+			continue
+		}
+
+		existing := mappingValue(dst, k.Value)
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, k, v)
+		case existing.Kind == yaml.SequenceNode && v.Kind == yaml.SequenceNode:
+			existing.Content = append(existing.Content, v.Content...)
+		case existing.Kind == yaml.MappingNode && v.Kind == yaml.MappingNode:
+			if err := mergeMapping(existing, v, name); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("line %d: %s is already set by another file", k.Line, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+}
+
+func TestLoad_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": `
+include:
+  - teams/*.yaml
+labels:
+  env: prod
+requests:
+  - api_path: "/repos/acme/web"
+    metrics:
+      - name: gh_stars
+        path: "stargazers_count"
+`,
+		"teams/api.yaml": `
+labels:
+  team: api
+requests:
+  - api_path: "/repos/acme/api"
+    metrics:
+      - name: gh_api_stars
+        path: "stargazers_count"
+`,
+		"teams/web.yaml": `
+requests:
+  - api_path: "/repos/acme/site"
+    metrics:
+      - name: gh_site_stars
+        path: "stargazers_count"
+`,
+	})
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"), "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	var paths []string
+	for _, req := range cfg.Requests {
+		paths = append(paths, req.ApiPath)
+	}
+	if got := strings.Join(paths, ","); got != "/repos/acme/web,/repos/acme/api,/repos/acme/site" {
+		t.Errorf("Expected the requests of every file in order, got %s", got)
+	}
+	if cfg.Labels["env"] != "prod" || cfg.Labels["team"] != "api" {
+		t.Errorf("Expected merged labels, got %v", cfg.Labels)
+	}
+}
+
+func TestLoad_Directory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"00-base.yaml": `
+metric_prefix: acme_
+`,
+		"10-web.yml": `
+requests:
+  - api_path: "/repos/acme/web"
+`,
+		"20-api.yaml": `
+requests:
+  - api_path: "/repos/acme/api"
+`,
+		"README.md": "not a config file",
+	})
+
+	cfg, err := Load(dir, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.MetricPrefix != "acme_" {
+		t.Errorf("Expected metric prefix acme_, got %q", cfg.MetricPrefix)
+	}
+	if len(cfg.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(cfg.Requests))
+	}
+
+	cfg, err = Load(filepath.Join(dir, "*-api.yaml"), "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Requests) != 1 || cfg.Requests[0].ApiPath != "/repos/acme/api" {
+		t.Errorf("Expected only the matched file, got %+v", cfg.Requests)
+	}
+
+	if _, err := Load(filepath.Join(dir, "*.json"), "", true); err == nil {
+		t.Error("Expected an error for a glob matching no config file")
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name: "setting in two files",
+			files: map[string]string{
+				"config.yaml": "include: other.yaml\nmetric_prefix: a_\n",
+				"other.yaml":  "metric_prefix: b_\n",
+			},
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"config.yaml": "include: missing.yaml\n",
+			},
+		},
+		{
+			name: "nested include",
+			files: map[string]string{
+				"config.yaml": "include: other.yaml\n",
+				"other.yaml":  "include: third.yaml\n",
+				"third.yaml":  "requests: []\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			if _, err := Load(filepath.Join(dir, "config.yaml"), "", true); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestValidate_IncludedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": "include: team.yaml\n",
+		"team.yaml": `
+requests:
+  - api_path: "/repos/acme/web"
+    metrics:
+      - name: "gh stars"
+        path: "stargazers_count"
+`,
+	})
+
+	var found bool
+	for _, p := range Validate(filepath.Join(dir, "config.yaml"), "", true) {
+		if p.File == filepath.Join(dir, "team.yaml") && p.Line == 5 {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected a problem on team.yaml:5 for the invalid metric name")
+	}
+}
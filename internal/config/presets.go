@@ -0,0 +1,96 @@
+package config
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed presets/*.yaml
+var presetFS embed.FS
+
+var presetFuncs = template.FuncMap{
+	"required": func(name string, v any) (any, error) {
+		if v == nil || v == "" {
+			return nil, fmt.Errorf("param %q is required", name)
+		}
+		return v, nil
+	},
+	// quote encodes a string as JSON, e.g. for a modifier argument.
+	"quote": func(v any) (string, error) {
+		b, err := json.Marshal(fmt.Sprint(v))
+		return string(b), err
+	},
+}
+
+// PresetConfig selects a built-in request bundle.
+type PresetConfig struct {
+	Name     string         `yaml:"name"`
+	Params   map[string]any `yaml:"params"`
+	Group    string         `yaml:"group"`    // group of every request of the preset
+	Instance string         `yaml:"instance"` // instance of every request of the preset
+}
+
+// PresetNames lists the built-in presets.
+func PresetNames() []string {
+	entries, err := fs.ReadDir(presetFS, "presets")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// presetBundle is the content of a preset file: either a list of requests or
+// a mapping with requests and the computed metrics derived from them.
+type presetBundle struct {
+	Requests []RequestConfig  `yaml:"requests"`
+	Computed []ComputedConfig `yaml:"computed"`
+}
+
+func (b *presetBundle) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&b.Requests)
+	}
+	type plain presetBundle
+	return value.Decode((*plain)(b))
+}
+
+// expandPreset renders a built-in preset with its params.
+func expandPreset(p PresetConfig) (*presetBundle, error) {
+	data, err := presetFS.ReadFile("presets/" + p.Name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("unknown preset %q, available: %s", p.Name, strings.Join(PresetNames(), ", "))
+	}
+
+	tmpl, err := template.New(p.Name).Funcs(presetFuncs).Funcs(DeferredTimeFuncs()).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("preset %q: %w", p.Name, err)
+	}
+	params := map[string]any{"Item": "{{ .Item }}"} // expanded by fan_out at runtime
+	for k, v := range p.Params {
+		params[k] = v
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return nil, fmt.Errorf("preset %q: %w", p.Name, err)
+	}
+
+	var bundle presetBundle
+	if err := yaml.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		return nil, fmt.Errorf("preset %q: %w", p.Name, err)
+	}
+	return &bundle, nil
+}
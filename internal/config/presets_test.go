@@ -0,0 +1,535 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad_CopilotPreset(t *testing.T) {
+	content := `
+presets:
+  - name: copilot
+    params:
+      org: acme
+      teams: [platform, data]
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(cfg.Requests))
+	}
+
+	billing := cfg.Requests[0]
+	if billing.ApiPath != "/orgs/acme/copilot/billing" {
+		t.Errorf("Unexpected api_path: %s", billing.ApiPath)
+	}
+	if billing.Metrics[0].Labels["org"] != `!"acme"` {
+		t.Errorf("Unexpected org label: %s", billing.Metrics[0].Labels["org"])
+	}
+
+	seats := cfg.Requests[1]
+	if seats.Paginate == nil || seats.Paginate.ItemsPath != "seats" {
+		t.Error("Expected seats request to be paginated over 'seats'")
+	}
+	if len(seats.Metrics) != 2 {
+		t.Fatalf("Expected 2 team metrics, got %d", len(seats.Metrics))
+	}
+	if seats.Metrics[1].Path != `seats.#(assigning_team.slug=="data")#` {
+		t.Errorf("Unexpected team path: %s", seats.Metrics[1].Path)
+	}
+	if seats.Metrics[1].Labels["team"] != `!"data"` {
+		t.Errorf("Unexpected team label: %s", seats.Metrics[1].Labels["team"])
+	}
+}
+
+func TestLoad_CopilotPresetWithoutTeams(t *testing.T) {
+	content := `
+presets:
+  - name: copilot
+    params:
+      org: acme
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Requests) != 1 {
+		t.Errorf("Expected 1 request, got %d", len(cfg.Requests))
+	}
+}
+
+func TestLoad_PresetMissingRequiredParam(t *testing.T) {
+	content := `
+presets:
+  - name: copilot
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err := Load(configPath, "", true)
+	if err == nil {
+		t.Error("Expected error for missing org param, got nil")
+	}
+}
+
+func TestLoad_UnknownPreset(t *testing.T) {
+	content := `
+presets:
+  - name: does_not_exist
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err := Load(configPath, "", true)
+	if err == nil {
+		t.Error("Expected error for unknown preset, got nil")
+	}
+}
+
+func TestPresetNames(t *testing.T) {
+	names := PresetNames()
+
+	found := false
+	for _, n := range names {
+		if n == "copilot" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected 'copilot' in preset names, got %v", names)
+	}
+}
+
+func TestLoad_ProjectsV2Preset(t *testing.T) {
+	content := `
+presets:
+  - name: projects_v2
+    params:
+      org: acme
+      project: 3
+      statuses: ["Todo", "In Progress", "Done"]
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	req := cfg.Requests[0]
+	if req.GraphQLPaginate == nil || req.GraphQLPaginate.NodesPath != "data.organization.projectV2.items.nodes" {
+		t.Error("Expected the project items connection to be paginated")
+	}
+	if !strings.Contains(req.Body, `projectV2(number: 3)`) {
+		t.Errorf("Expected project number in query, got %s", req.Body)
+	}
+
+	statusMetrics := 0
+	for _, m := range req.Metrics {
+		if m.Name == "github_project_items_by_status" {
+			statusMetrics++
+		}
+	}
+	if statusMetrics != 3 {
+		t.Errorf("Expected 3 status metrics, got %d", statusMetrics)
+	}
+}
+
+func TestLoad_SponsorsPreset(t *testing.T) {
+	content := `
+presets:
+  - name: sponsors
+    params:
+      login: octocat
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	req := cfg.Requests[0]
+	if req.Method != "POST" || req.ApiPath != "/graphql" {
+		t.Errorf("Expected a GraphQL request, got %s %s", req.Method, req.ApiPath)
+	}
+	if !strings.Contains(req.Body, `repositoryOwner(login: \"octocat\")`) {
+		t.Errorf("Expected login in query, got %s", req.Body)
+	}
+	if len(req.Metrics) != 3 {
+		t.Errorf("Expected 3 metrics, got %d", len(req.Metrics))
+	}
+
+	tiers := cfg.Requests[1]
+	if tiers.GraphQLPaginate == nil || tiers.GraphQLPaginate.NodesPath != "data.repositoryOwner.sponsorshipsAsMaintainer.nodes" {
+		t.Errorf("Expected the sponsorships to be paginated, got %+v", tiers.GraphQLPaginate)
+	}
+	for _, metric := range tiers.Metrics {
+		if metric.GroupLabel != "tier" {
+			t.Errorf("Expected %s to be grouped by tier, got %q", metric.Name, metric.GroupLabel)
+		}
+	}
+}
+
+func TestLoad_RatiosPreset(t *testing.T) {
+	content := `
+presets:
+  - name: ratios
+    params:
+      repo: acme/app
+      runs: 50
+  - name: ratios
+    params:
+      repo: acme/lib
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Requests) != 10 {
+		t.Fatalf("Expected 10 requests, got %d", len(cfg.Requests))
+	}
+	if cfg.Requests[0].Interval != "15m" {
+		t.Errorf("Expected search requests to default to 15m, got %q", cfg.Requests[0].Interval)
+	}
+	if cfg.Requests[4].ApiPath != "/repos/acme/app/actions/runs?status=completed&per_page=50" {
+		t.Errorf("Unexpected runs api_path: %s", cfg.Requests[4].ApiPath)
+	}
+	if len(cfg.Computed) != 3 {
+		t.Errorf("Expected duplicate computed metrics to be dropped, got %d", len(cfg.Computed))
+	}
+}
+
+func TestLoad_ContentsPreset(t *testing.T) {
+	content := `
+presets:
+  - name: contents
+    params:
+      repo: acme/app
+      ref: main
+      files: ["CODEOWNERS", ".github/dependabot.yml"]
+      values:
+        - name: major
+          file: VERSION
+          match: '^(\d+)\.'
+        - name: coverage
+          file: badges/coverage.json
+          path: 'message|@match:"([0-9.]+)"'
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Requests) != 4 {
+		t.Fatalf("Expected 4 requests, got %d", len(cfg.Requests))
+	}
+	presence := cfg.Requests[0]
+	if presence.ApiPath != "/repos/acme/app/contents/CODEOWNERS?ref=main" {
+		t.Errorf("Unexpected api_path: %s", presence.ApiPath)
+	}
+	if presence.StatusValues[404] != 0 || len(presence.ExpectStatus) != 2 {
+		t.Errorf("Expected 404 to be mapped to 0, got %v %v", presence.ExpectStatus, presence.StatusValues)
+	}
+	if got := cfg.Requests[2].Metrics[0].Path; got != `content|@base64|@match:"^(\\d+)\\."` {
+		t.Errorf("Unexpected match path: %s", got)
+	}
+	if got := cfg.Requests[3].Metrics[0].Path; got != `content|@base64|@fromstr|message|@match:"([0-9.]+)"` {
+		t.Errorf("Unexpected JSON path: %s", got)
+	}
+}
+
+func TestLoad_LibraryPresets(t *testing.T) {
+	tests := []struct {
+		preset   string
+		requests int
+		metrics  []string
+	}{
+		{"repo_basics", 1, []string{"github_repo_stars", "github_repo_archived", "github_repo_pushed_timestamp_seconds"}},
+		{"actions", 3, []string{"github_actions_workflows", "github_actions_runs_in_progress", "github_actions_workflow_runs_completed", "github_actions_workflow_run_duration_seconds"}},
+		{"issues_prs", 5, []string{"github_repo_issues_open", "github_repo_pull_requests_draft", "github_repo_oldest_open_pull_request_timestamp_seconds"}},
+		{"traffic", 3, []string{"github_repo_traffic_views", "github_repo_traffic_unique_cloners", "github_repo_traffic_views_daily", "github_repo_traffic_referrer_views"}},
+		{"checks", 2, []string{"github_commit_status", "github_commit_status_context", "github_commit_check_runs_failed", "github_commit_check_run_conclusion"}},
+		{"community", 2, []string{"github_repo_forks_created_recently", "github_repo_contributors"}},
+		{"security", 3, []string{"github_dependabot_alerts_open_by_severity", "github_code_scanning_alerts_open_by_severity", "github_secret_scanning_alerts_open"}},
+		{"deployments", 1, []string{"github_actions_runs_waiting_approval", "github_deployments_pending_approval"}},
+		{"refs", 2, []string{"github_repo_tags", "github_repo_branches"}},
+	}
+
+	for _, tt := range tests {
+		content := `
+presets:
+  - name: ` + tt.preset + `
+    params:
+      repo: acme/app
+`
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		cfg, err := Load(configPath, "", true)
+		if err != nil {
+			t.Errorf("Failed to load preset %s: %v", tt.preset, err)
+			continue
+		}
+		if len(cfg.Requests) != tt.requests {
+			t.Errorf("Expected %d requests for %s, got %d", tt.requests, tt.preset, len(cfg.Requests))
+		}
+		declared := make(map[string]bool)
+		for _, req := range cfg.Requests {
+			if !strings.Contains(req.ApiPath, "acme/app") {
+				t.Errorf("Expected repo in %s api_path, got %s", tt.preset, req.ApiPath)
+			}
+			for _, metric := range req.Metrics {
+				declared[metric.Name] = true
+				if metric.Labels["repo"] != `!"acme/app"` {
+					t.Errorf("Expected a repo label on %s, got %v", metric.Name, metric.Labels)
+				}
+			}
+		}
+		for _, name := range tt.metrics {
+			if !declared[name] {
+				t.Errorf("Expected metric %s in preset %s", name, tt.preset)
+			}
+		}
+	}
+}
+
+func TestLoad_ReleasesPreset(t *testing.T) {
+	content := `
+presets:
+  - name: releases
+    params:
+      repo: acme/app
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	req := cfg.Requests[0]
+	if req.ApiPath != "/repos/acme/app/releases/latest" {
+		t.Errorf("Expected the latest release endpoint, got %s", req.ApiPath)
+	}
+	if len(req.ExpectStatus) != 2 || req.ExpectStatus[1] != 404 {
+		t.Errorf("Expected 404 to be accepted for repositories without releases, got %v", req.ExpectStatus)
+	}
+	if len(req.Metrics) != 3 {
+		t.Fatalf("Expected 3 metrics, got %d", len(req.Metrics))
+	}
+	if req.Metrics[0].ValueType != TypeSemver {
+		t.Errorf("Expected a semver version metric, got %q", req.Metrics[0].ValueType)
+	}
+	if req.Metrics[2].Expr == "" {
+		t.Error("Expected the days since release to be computed with expr")
+	}
+
+	assets := cfg.Requests[1]
+	if assets.ApiPath != "/repos/acme/app/releases?per_page=10" {
+		t.Errorf("Expected the 10 latest releases by default, got %s", assets.ApiPath)
+	}
+	if assets.Metrics[0].MetricType != MetricCounter || assets.Metrics[0].GroupLabel != "tag,asset" {
+		t.Errorf("Expected an asset download counter per tag and asset, got %+v", assets.Metrics[0])
+	}
+}
+
+func TestLoad_ChecksPresetRef(t *testing.T) {
+	content := `
+presets:
+  - name: checks
+    params:
+      repo: acme/app
+      ref: release-1.x
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if cfg.Requests[1].ApiPath != "/repos/acme/app/commits/release-1.x/check-runs?per_page=100" {
+		t.Errorf("Expected the check runs of the ref, got %s", cfg.Requests[1].ApiPath)
+	}
+	for _, req := range cfg.Requests {
+		for _, metric := range req.Metrics {
+			if metric.Labels["ref"] != `!"release-1.x"` {
+				t.Errorf("Expected a ref label on %s, got %v", metric.Name, metric.Labels)
+			}
+		}
+	}
+}
+
+func TestLoad_TrafficPresetTimestamps(t *testing.T) {
+	for _, timestamps := range []bool{false, true} {
+		content := fmt.Sprintf(`
+presets:
+  - name: traffic
+    params:
+      repo: acme/app
+      timestamps: %t
+`, timestamps)
+
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		cfg, err := Load(configPath, "", true)
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+
+		for _, metric := range cfg.Requests[0].Metrics {
+			daily := strings.HasSuffix(metric.Name, "_daily")
+			if got := metric.Timestamp != ""; got != (daily && timestamps) {
+				t.Errorf("Unexpected timestamp %q on %s with timestamps=%t", metric.Timestamp, metric.Name, timestamps)
+			}
+		}
+	}
+}
+
+func TestLoad_BillingPreset(t *testing.T) {
+	content := `
+presets:
+  - name: billing
+    params:
+      org: acme
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(cfg.Requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(cfg.Requests))
+	}
+	for i, endpoint := range []string{"actions", "packages", "shared-storage"} {
+		if want := "/orgs/acme/settings/billing/" + endpoint; cfg.Requests[i].ApiPath != want {
+			t.Errorf("Expected %s, got %s", want, cfg.Requests[i].ApiPath)
+		}
+	}
+	byOS := cfg.Requests[0].Metrics[3]
+	if byOS.GroupBy != "key" || byOS.GroupLabel != "os" {
+		t.Errorf("Expected minutes to be grouped by runner OS, got group_by %q, group_label %q", byOS.GroupBy, byOS.GroupLabel)
+	}
+}
+
+func TestLoad_AuditLogAndSCIMPresets(t *testing.T) {
+	content := `
+presets:
+  - name: audit_log
+    params:
+      org: acme
+      days: 7
+  - name: scim
+    params:
+      org: acme
+`
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(cfg.Requests))
+	}
+
+	audit := cfg.Requests[0]
+	if want := "/orgs/acme/audit-log?phrase=created:>={{ daysAgo 7 }}&per_page=100"; audit.ApiPath != want {
+		t.Errorf("Expected the window to be rendered on every fetch, got %s", audit.ApiPath)
+	}
+	if audit.Paginate == nil || audit.Paginate.Type != PaginateLink || audit.Paginate.MaxPages != 10 {
+		t.Errorf("Expected link pagination over at most 10 pages, got %+v", audit.Paginate)
+	}
+	if got := audit.Metrics[0].GroupBy; got != "action" {
+		t.Errorf("Expected audit events to be grouped by action, got %q", got)
+	}
+
+	scim := cfg.Requests[1]
+	if scim.Paginate == nil || scim.Paginate.Type != PaginateSCIM {
+		t.Errorf("Expected SCIM pagination, got %+v", scim.Paginate)
+	}
+	if got := scim.Metrics[0].Path; got != "totalResults" {
+		t.Errorf("Expected provisioned users to be read from totalResults, got %q", got)
+	}
+}
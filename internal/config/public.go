@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/schedule"
+)
+
+// AnonymousRateLimit is the number of unauthenticated API calls GitHub
+// allows per hour and IP address.
+const AnonymousRateLimit = 60
+
+// applyPublic sets up the token-less public mode: no token is sent, requests
+// are fetched one at a time in the background, spread so that one fetch of
+// each stays within the anonymous rate limit, and every series is labeled
+// authenticated="false".
+func (c *Config) applyPublic() error {
+	c.Token = ""
+	c.TokenFile = ""
+	c.MaxConcurrent = 1
+	if c.Labels == nil {
+		c.Labels = make(map[string]string)
+	}
+	c.Labels["authenticated"] = "false"
+
+	for name, inst := range c.Instances {
+		if inst.Token != "" {
+			return fmt.Errorf("instance %q: public mode does not send tokens", name)
+		}
+	}
+
+	var reqs []*RequestConfig
+	for i := range c.Requests {
+		reqs = append(reqs, &c.Requests[i])
+	}
+	for i := range c.Discovery {
+		for j := range c.Discovery[i].Requests {
+			reqs = append(reqs, &c.Discovery[i].Requests[j])
+		}
+	}
+	for _, req := range reqs {
+		if req.Token != "" {
+			return fmt.Errorf("request %q: public mode does not send tokens", req.ApiPath)
+		}
+	}
+
+	// Discovered repositories and pages multiply the calls, this only
+	// accounts for one call per request and repository listing.
+	calls := len(reqs) + len(c.Discovery)
+	least := max(time.Hour*time.Duration(calls)/AnonymousRateLimit, time.Minute)
+	for _, req := range reqs {
+		if req.Interval != "" {
+			sched, err := schedule.Parse(req.Interval)
+			if err != nil {
+				return fmt.Errorf("request %q: %w", req.ApiPath, err)
+			}
+			next := sched.Next(time.Now())
+			if sched.Next(next).Sub(next) >= least {
+				continue
+			}
+		}
+		req.Interval = least.String()
+	}
+	return nil
+}
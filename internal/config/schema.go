@@ -0,0 +1,74 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// schemaEnums lists the accepted values of the enumerated settings.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeFor[AggregateType]():   {string(AggregateSum), string(AggregateCount), string(AggregateMax), string(AggregateMin), string(AggregateAvg), string(AggregateFirst)},
+	reflect.TypeFor[EmptyPolicy]():     {string(EmptySkip), string(EmptyZero)},
+	reflect.TypeFor[MissingPolicy]():   {string(MissingZero), string(MissingSkip), string(MissingDefault)},
+	reflect.TypeFor[ResponseFormat]():  {string(FormatJSON), string(FormatText), string(FormatCSV), string(FormatXML)},
+	reflect.TypeFor[MetricType]():      {string(MetricGauge), string(MetricCounter), string(MetricHistogram), string(MetricDelta)},
+	reflect.TypeFor[MetricValueType](): {string(TypeFloat), string(TypeDate), string(TypeBool), string(TypeChecksum), string(TypeSemver)},
+	reflect.TypeFor[PaginateType]():    {string(PaginateLink), string(PaginateSCIM), string(PaginateCount)},
+}
+
+// Schema returns a JSON Schema of the rendered config file, derived from
+// the yaml tags of Config. Unknown keys are rejected, as with strict loading.
+func Schema() map[string]any {
+	s := schemaOf(reflect.TypeFor[Config]())
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "github-exporter configuration"
+	props := s["properties"].(map[string]any)
+	props["include"] = map[string]any{
+		"anyOf": []any{
+			map[string]any{"type": "string"},
+			map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+	}
+	return s
+}
+
+func schemaOf(t reflect.Type) map[string]any {
+	if values, ok := schemaEnums[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		// JSON object keys are strings, integer keys such as status codes
+		// are written as strings too.
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		props := make(map[string]any)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(f.Name)
+			}
+			props[name] = schemaOf(f.Type)
+		}
+		return map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	default:
+		return map[string]any{}
+	}
+}
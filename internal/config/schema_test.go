@@ -0,0 +1,55 @@
+package config
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+	var schema struct {
+		AdditionalProperties bool `json:"additionalProperties"`
+		Properties           map[string]struct {
+			Type  string `json:"type"`
+			Items struct {
+				Properties map[string]struct {
+					Items struct {
+						Properties map[string]struct {
+							Enum []string `json:"enum"`
+						} `json:"properties"`
+					} `json:"items"`
+				} `json:"properties"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+
+	if schema.AdditionalProperties {
+		t.Error("Expected unknown top-level keys to be rejected")
+	}
+	for _, key := range []string{"github_api_url", "requests", "include", "remote_write"} {
+		if _, ok := schema.Properties[key]; !ok {
+			t.Errorf("Expected property %s", key)
+		}
+	}
+	if _, ok := schema.Properties["Hash"]; ok {
+		t.Error("Expected fields without a yaml key to be left out")
+	}
+	if got := schema.Properties["max_concurrent_requests"].Type; got != "integer" {
+		t.Errorf("Expected max_concurrent_requests to be an integer, got %q", got)
+	}
+
+	metric := schema.Properties["requests"].Items.Properties["metrics"].Items.Properties
+	if !slices.Contains(metric["value_type"].Enum, "date") {
+		t.Errorf("Expected value_type to enumerate date, got %v", metric["value_type"].Enum)
+	}
+	if _, ok := metric["track_window"]; !ok {
+		t.Error("Expected the metric properties to include track_window")
+	}
+}
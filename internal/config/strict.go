@@ -0,0 +1,57 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var unknownFieldRE = regexp.MustCompile(`^line (\d+): field (.+?) not found in type `)
+
+// unknownFields decodes a rendered config file strictly and reports the keys
+// that match no setting.
+func unknownFields(data []byte) []Problem {
+	var cfg struct {
+		Config  `yaml:",inline"`
+		Include any `yaml:"include"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&cfg)
+	var typeErr *yaml.TypeError
+	if err == nil || errors.Is(err, io.EOF) || !errors.As(err, &typeErr) {
+		// Syntax and type errors are reported by the regular decoding.
+		return nil
+	}
+
+	var problems []Problem
+	for _, msg := range typeErr.Errors {
+		m := unknownFieldRE.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		problems = append(problems, Problem{Line: line, Message: fmt.Sprintf("unknown field %q", m[2])})
+	}
+	return problems
+}
+
+// checkFields returns an error listing the unknown keys of every doc.
+func checkFields(docs []configDoc) error {
+	var msgs []string
+	for _, doc := range docs {
+		for _, p := range unknownFields(doc.data) {
+			msgs = append(msgs, fmt.Sprintf("%s:%d: %s", doc.path, p.Line, p.Message))
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return nil
+}
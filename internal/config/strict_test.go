@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unknownFieldConfig = `
+requests:
+  - api_path: "/repos/acme/web"
+    metrics:
+      - name: gh_stars
+        path: "stargazers_count"
+        value_tpye: "date"
+`
+
+func TestLoad_UnknownField(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(unknownFieldConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err := Load(configPath, "", true)
+	if err == nil {
+		t.Fatal("Expected an error for an unknown field")
+	}
+	if want := configPath + `:7: unknown field "value_tpye"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error to contain %q, got %v", want, err)
+	}
+
+	problems := Validate(configPath, "", true)
+	if len(problems) != 1 || problems[0].Line != 7 {
+		t.Errorf("Expected one problem on line 7, got %+v", problems)
+	}
+}
+
+func TestLoad_NotStrict(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(unknownFieldConfig), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(configPath, "", false); err != nil {
+		t.Errorf("Expected unknown fields to be ignored, got %v", err)
+	}
+	if problems := Validate(configPath, "", false); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %+v", problems)
+	}
+}
+
+func TestLoad_UnknownFieldInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml": "include: team.yaml\nmetric_prefx: acme_\n",
+		"team.yaml":   "requests: []\n",
+	})
+
+	_, err := Load(filepath.Join(dir, "config.yaml"), "", true)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "metric_prefx"`) {
+		t.Errorf("Expected an unknown field error, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"text/template"
+	"time"
+)
+
+// Time functions make request templates relative to the time of the fetch,
+// e.g. created:>{{ daysAgo 30 }} in a search query. They are evaluated on
+// every fetch: loading the config and expanding discovery and probe
+// templates write them back unchanged.
+
+// TimeFuncs returns the time functions evaluated at now, in UTC.
+func TimeFuncs(now time.Time) template.FuncMap {
+	now = now.UTC()
+	return template.FuncMap{
+		"now": func() time.Time { return now },
+		"daysAgo": func(days int) string {
+			return now.AddDate(0, 0, -days).Format(time.DateOnly)
+		},
+		"ago": func(duration string) (string, error) {
+			d, err := time.ParseDuration(duration)
+			if err != nil {
+				return "", err
+			}
+			return now.Add(-d).Format(time.RFC3339), nil
+		},
+	}
+}
+
+// DeferredTimeFuncs returns functions named like those of TimeFuncs that
+// render their own call, keeping it for the fetch.
+func DeferredTimeFuncs() template.FuncMap {
+	return template.FuncMap{
+		"now":     func() deferredNow { return deferredNow{} },
+		"daysAgo": func(days int) string { return fmt.Sprintf("{{ daysAgo %d }}", days) },
+		"ago": func(duration string) (string, error) {
+			// Checked now rather than on every fetch.
+			if _, err := time.ParseDuration(duration); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("{{ ago %q }}", duration), nil
+		},
+	}
+}
+
+// deferredNow stands for now, and for the methods of time.Time templates
+// call on it.
+type deferredNow struct{}
+
+func (deferredNow) String() string              { return "{{ now }}" }
+func (deferredNow) Format(layout string) string { return fmt.Sprintf("{{ now.Format %q }}", layout) }
+func (deferredNow) Unix() string                { return "{{ now.Unix }}" }
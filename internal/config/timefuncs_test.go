@@ -0,0 +1,78 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTimeFuncs(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		text string
+		want string
+	}{
+		{"{{ daysAgo 30 }}", "2024-03-01"},
+		{`{{ ago "2h" }}`, "2024-03-31T10:30:00Z"},
+		{`{{ now.Format "2006-01" }}`, "2024-03"},
+		{"{{ now.Unix }}", "1711888200"},
+	} {
+		tmpl, err := template.New("test").Funcs(TimeFuncs(now)).Parse(tt.text)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", tt.text, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, nil); err != nil {
+			t.Fatalf("Failed to execute %s: %v", tt.text, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("Expected %s for %s, got %s", tt.want, tt.text, buf.String())
+		}
+	}
+}
+
+func TestLoad_TimeFuncsDeferred(t *testing.T) {
+	content := `
+requests:
+  - api_path: '/search/issues?q=created:>{{ daysAgo 30 }}'
+    query_params:
+      since: '{{ ago "168h" }}'
+      month: '{{ now.Format "2006-01" }}'
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath, "", true)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	req := cfg.Requests[0]
+	if want := "/search/issues?q=created:>{{ daysAgo 30 }}"; req.ApiPath != want {
+		t.Errorf("Expected %s, got %s", want, req.ApiPath)
+	}
+	if want := `{{ ago "168h" }}`; req.QueryParams["since"] != want {
+		t.Errorf("Expected %s, got %s", want, req.QueryParams["since"])
+	}
+	if want := `{{ now.Format "2006-01" }}`; req.QueryParams["month"] != want {
+		t.Errorf("Expected %s, got %s", want, req.QueryParams["month"])
+	}
+}
+
+func TestLoad_TimeFuncsInvalidDuration(t *testing.T) {
+	content := `
+requests:
+  - api_path: '/search/issues?q=created:>{{ ago "a week" }}'
+`
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	if _, err := Load(configPath, "", true); err == nil {
+		t.Error("Expected an error for an invalid duration")
+	}
+}
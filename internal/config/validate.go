@@ -0,0 +1,217 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Problem is a configuration error reported by Validate. Line is 0 when the
+// error cannot be tied to a line of the file. File is the included file or
+// directory entry the line belongs to, empty for the main config file.
+type Problem struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validate checks the config file at path and reports every problem found,
+// rather than stopping at the first one like Load. With strict, keys that
+// match no setting are problems.
+func Validate(path string, githubUser string, strict bool) []Problem {
+	// Unknown keys are reported with the other problems below.
+	data, docs, err := renderAll(path, githubUser, false)
+	if err != nil {
+		return []Problem{{Message: err.Error()}}
+	}
+
+	v := &validator{metrics: make(map[string]metricDecl)}
+	for _, d := range docs {
+		if d.path != path {
+			v.file = d.path
+		}
+		v.document(d.root)
+		if strict {
+			for _, p := range unknownFields(d.data) {
+				v.addf(p.Line, "%s", p.Message)
+			}
+		}
+	}
+	v.file = ""
+
+	// Then everything Load checks: intervals, pagination, presets and
+	// computed metrics. Metric conflicts already reported above with their
+	// line are not repeated.
+	if _, err := parse(data, !v.conflicts); err != nil {
+		v.problems = append(v.problems, Problem{Message: err.Error()})
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].File != v.problems[j].File {
+			return v.problems[i].File < v.problems[j].File
+		}
+		return v.problems[i].Line < v.problems[j].Line
+	})
+	return v.problems
+}
+
+// document checks the requests of a top-level config mapping.
+func (v *validator) document(doc *yaml.Node) {
+	for _, req := range sequence(mappingValue(doc, "requests")) {
+		v.request(req)
+	}
+	for _, t := range sequence(mappingValue(doc, "targets")) {
+		label := DefaultTargetLabel
+		if n := mappingValue(t, "label"); n != nil && n.Value != "" {
+			label = n.Value
+		}
+		for _, req := range sequence(mappingValue(t, "requests")) {
+			v.request(req, label)
+		}
+	}
+	for _, d := range sequence(mappingValue(doc, "discovery")) {
+		for _, req := range sequence(mappingValue(d, "requests")) {
+			v.request(req, "repo")
+		}
+	}
+}
+
+// metricDecl is the first declaration of a metric name.
+type metricDecl struct {
+	file      string
+	line      int
+	metric    MetricConfig
+	labelKeys []string
+}
+
+type validator struct {
+	metrics   map[string]metricDecl
+	problems  []Problem
+	file      string // file being checked, empty for the main config file
+	conflicts bool   // a metric conflict was reported
+}
+
+func (v *validator) addf(line int, format string, args ...any) {
+	v.problems = append(v.problems, Problem{File: v.file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// request checks the metrics of a request. extraKeys are the labels the
+// exporter adds on its own, e.g. repo for discovered requests.
+func (v *validator) request(req *yaml.Node, extraKeys ...string) {
+	format := mappingValue(req, "response_format")
+	textResponse := format != nil && format.Value == string(FormatText)
+	for _, metric := range sequence(mappingValue(req, "metrics")) {
+		var mc MetricConfig
+		if err := metric.Decode(&mc); err != nil {
+			v.addf(metric.Line, "%v", err)
+			continue
+		}
+
+		nameLine := lineOf(metric, "name")
+		switch {
+		case mc.Name == "":
+			v.addf(metric.Line, "metric name is required")
+		case !metricNameRE.MatchString(mc.Name):
+			v.addf(nameLine, "metric name %q is not a valid Prometheus metric name", mc.Name)
+		}
+		if mc.Path == "" && mc.Expr == "" && !textResponse {
+			v.addf(metric.Line, "metric %q: path or expr is required", mc.Name)
+		}
+		switch mc.Aggregate {
+		case "", AggregateSum, AggregateCount, AggregateMax, AggregateMin, AggregateAvg, AggregateFirst:
+		default:
+			v.addf(lineOf(metric, "aggregate"), "metric %q: unknown aggregate %q, expected sum, count, max, min, avg or first", mc.Name, mc.Aggregate)
+		}
+		switch mc.ValueType {
+		case "", TypeFloat, TypeDate, TypeBool, TypeChecksum, TypeSemver:
+		default:
+			v.addf(lineOf(metric, "value_type"), "metric %q: unknown value_type %q, expected float, date, bool, checksum or semver", mc.Name, mc.ValueType)
+		}
+		switch mc.MetricType {
+		case "", MetricGauge, MetricCounter, MetricHistogram, MetricDelta:
+		default:
+			v.addf(lineOf(metric, "metric_type"), "metric %q: unknown metric_type %q, expected gauge, counter, delta or histogram", mc.Name, mc.MetricType)
+		}
+		for _, key := range slices.Sorted(maps.Keys(mc.Labels)) {
+			if !labelNameRE.MatchString(key) || key == "api_path" {
+				v.addf(lineOf(metric, "labels"), "metric %q: invalid label name %q", mc.Name, key)
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(mc.ConstLabels)) {
+			if _, ok := mc.Labels[key]; ok || !labelNameRE.MatchString(key) || key == "api_path" {
+				v.addf(lineOf(metric, "const_labels"), "metric %q: invalid const label %q", mc.Name, key)
+			}
+		}
+
+		if mc.Name == "" {
+			continue
+		}
+		labelKeys := append([]string{"api_path"}, slices.Collect(maps.Keys(mc.Labels))...)
+		for _, k := range extraKeys {
+			if _, ok := mc.Labels[k]; !ok {
+				labelKeys = append(labelKeys, k)
+			}
+		}
+		if mc.GroupBy != "" {
+			paths, labels := mc.GroupKeys()
+			if labels == nil {
+				for _, p := range paths {
+					labels = append(labels, invalidLabelChars.ReplaceAllString(p, "_"))
+				}
+			}
+			labelKeys = append(labelKeys, labels...)
+		}
+		slices.Sort(labelKeys)
+		if prev, ok := v.metrics[mc.Name]; ok {
+			if conflict := metricConflict(prev.metric, prev.labelKeys, mc, labelKeys); conflict != "" {
+				where := fmt.Sprintf("line %d", prev.line)
+				if prev.file != v.file {
+					where = fmt.Sprintf("%s:%d", cmp.Or(prev.file, "the main config file"), prev.line)
+				}
+				v.addf(nameLine, "metric %q is already declared on %s with a different %s", mc.Name, where, conflict)
+				v.conflicts = true
+			}
+			continue
+		}
+		v.metrics[mc.Name] = metricDecl{file: v.file, line: nameLine, metric: mc, labelKeys: labelKeys}
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// sequence returns the items of a sequence node, or nil.
+func sequence(n *yaml.Node) []*yaml.Node {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return n.Content
+}
+
+// lineOf returns the line of key in a mapping node, falling back to the
+// line of the mapping itself.
+func lineOf(n *yaml.Node, key string) int {
+	if value := mappingValue(n, key); value != nil {
+		return value.Line
+	}
+	return n.Line
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate_Valid(t *testing.T) {
+	content := `requests:
+  - api_path: "/users/test"
+    metrics:
+      - name: github_followers
+        path: "followers"
+        help: "Total followers"
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if problems := Validate(configPath, "", true); len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v", problems)
+	}
+}
+
+func TestValidate_ReportsEveryProblemWithLine(t *testing.T) {
+	content := `requests:
+  - api_path: "/users/test"
+    metrics:
+      - name: github-followers
+        path: "followers"
+        help: "Total followers"
+      - name: github_repos
+        path: "repos"
+        aggregate: "median"
+        help: "Repositories"
+  - api_path: "/users/other"
+    metrics:
+      - name: github_repos
+        path: "repos"
+        help: "Other repositories"
+      - name: github_created
+        path: "created_at"
+        value_type: "time"
+        help: "Creation date"
+`
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	problems := Validate(configPath, "", true)
+	expected := []struct {
+		line     int
+		contains string
+	}{
+		{4, "not a valid Prometheus metric name"},
+		{9, "unknown aggregate"},
+		{13, "already declared on line 7"},
+		{18, "unknown value_type"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), problems)
+	}
+	for i, e := range expected {
+		if problems[i].Line != e.line || !strings.Contains(problems[i].Message, e.contains) {
+			t.Errorf("Expected problem %q on line %d, got %q on line %d", e.contains, e.line, problems[i].Message, problems[i].Line)
+		}
+	}
+}
+
+func TestValidate_ConflictsLikeLoad(t *testing.T) {
+	content := `requests:
+  - api_path: "/repos/acme/app"
+    metrics:
+      - name: github_stars
+        path: "stargazers_count"
+        help: "Stars"
+      - name: github_forks
+        path: "forks_count"
+        help: "Forks"
+        const_labels: {team: "core"}
+  - api_path: "/repos/acme/lib"
+    metrics:
+      - name: github_stars
+        path: "stargazers_count"
+        metric_type: counter
+        help: "Stars"
+      - name: github_forks
+        path: "forks_count"
+        help: "Forks"
+        const_labels: {team: "libs"}
+`
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if _, err := Load(configPath, "", true); err == nil {
+		t.Fatal("Expected Load to reject the conflicting declarations")
+	}
+	problems := Validate(configPath, "", true)
+	expected := []struct {
+		line     int
+		contains string
+	}{
+		{13, "different metric_type"},
+		{17, "different const_labels"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %v", len(expected), problems)
+	}
+	for i, e := range expected {
+		if problems[i].Line != e.line || !strings.Contains(problems[i].Message, e.contains) {
+			t.Errorf("Expected problem %q on line %d, got %q on line %d", e.contains, e.line, problems[i].Message, problems[i].Line)
+		}
+	}
+}
+
+func TestValidate_InvalidYAML(t *testing.T) {
+	content := "requests:\n  - api_path: [\n"
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if problems := Validate(configPath, "", true); len(problems) != 1 {
+		t.Errorf("Expected 1 problem, got %v", problems)
+	}
+}
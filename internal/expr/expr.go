@@ -0,0 +1,198 @@
+// Package expr evaluates arithmetic expressions over the fields of a JSON
+// document, e.g. "open_issues_count / (stargazers_count + 1)" or
+// "now() - parse_time(pushed_at)".
+//
+// Bare identifiers are gjson paths made of letters, digits and "_.#@\";
+// paths containing other characters, such as queries, are written between
+// backquotes. Strings are quoted with " or '. The operators are + - * / %
+// and the functions now(), parse_time(s), abs(x), min(x, ...) and
+// max(x, ...).
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lookup resolves a path to a float64, a string, or nil when the path does
+// not exist.
+type Lookup func(path string) any
+
+// Expr is a parsed expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Parse parses src.
+func Parse(src string) (*Expr, error) {
+	p := &parser{lex: lexer{src: src}}
+	p.next()
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", src, err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("expr %q: unexpected %q at offset %d", src, p.tok.text, p.tok.pos)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression, resolving paths with lookup. Missing paths
+// evaluate to 0, like metric paths.
+func (e *Expr) Eval(lookup Lookup) (float64, error) {
+	v, err := e.root.eval(lookup)
+	if err != nil {
+		return 0, fmt.Errorf("expr %q: %w", e.src, err)
+	}
+	f, err := number(v)
+	if err != nil {
+		return 0, fmt.Errorf("expr %q: %w", e.src, err)
+	}
+	return f, nil
+}
+
+// number converts an operand to a float64. Numeric strings are accepted
+// since GitHub returns some counts as strings.
+func number(v any) (float64, error) {
+	switch v := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("unexpected value %v", v)
+}
+
+type node interface {
+	eval(lookup Lookup) (any, error)
+}
+
+type literal struct{ value any }
+
+func (n literal) eval(Lookup) (any, error) {
+	return n.value, nil
+}
+
+type path struct{ path string }
+
+func (n path) eval(lookup Lookup) (any, error) {
+	return lookup(n.path), nil
+}
+
+type unary struct{ operand node }
+
+func (n unary) eval(lookup Lookup) (any, error) {
+	v, err := n.operand.eval(lookup)
+	if err != nil {
+		return nil, err
+	}
+	f, err := number(v)
+	return -f, err
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (n binary) eval(lookup Lookup) (any, error) {
+	operands := make([]float64, 2)
+	for i, operand := range []node{n.left, n.right} {
+		v, err := operand.eval(lookup)
+		if err != nil {
+			return nil, err
+		}
+		if operands[i], err = number(v); err != nil {
+			return nil, err
+		}
+	}
+	a, b := operands[0], operands[1]
+	switch n.op {
+	case '+':
+		return a + b, nil
+	case '-':
+		return a - b, nil
+	case '*':
+		return a * b, nil
+	case '/':
+		if b == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	default:
+		if b == 0 {
+			return nil, fmt.Errorf("modulo by zero")
+		}
+		return math.Mod(a, b), nil
+	}
+}
+
+type call struct {
+	name string
+	args []node
+}
+
+// arity is the number of arguments of every function, -1 for variadic
+// functions taking at least one.
+var arity = map[string]int{
+	"now":        0,
+	"parse_time": 1,
+	"abs":        1,
+	"min":        -1,
+	"max":        -1,
+}
+
+func (n call) eval(lookup Lookup) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(lookup)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	switch n.name {
+	case "now":
+		return float64(time.Now().Unix()), nil
+	case "parse_time":
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("parse_time: expected a string, got %v", args[0])
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("parse_time: %w", err)
+		}
+		return float64(t.Unix()), nil
+	case "abs":
+		f, err := number(args[0])
+		return math.Abs(f), err
+	default: // min, max
+		var result float64
+		for i, arg := range args {
+			f, err := number(arg)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 || (n.name == "min" && f < result) || (n.name == "max" && f > result) {
+				result = f
+			}
+		}
+		return result, nil
+	}
+}
@@ -0,0 +1,106 @@
+package expr
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var repo = map[string]any{
+	"open_issues_count":    float64(9),
+	"stargazers_count":     float64(2),
+	"pushed_at":            "2024-01-01T00:00:00Z",
+	"owner.login":          "acme",
+	"size":                 "42",
+	`#(type=="Push").size`: float64(3),
+}
+
+func lookup(p string) any {
+	return repo[p]
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected float64
+	}{
+		{"open_issues_count / (stargazers_count + 1)", 3},
+		{"open_issues_count - stargazers_count * 2", 5},
+		{"-stargazers_count + 10", 8},
+		{"open_issues_count % 4", 1},
+		{"parse_time(pushed_at)", 1704067200},
+		{"parse_time('2024-01-01T00:01:00Z') - parse_time(pushed_at)", 60},
+		{"abs(stargazers_count - open_issues_count)", 7},
+		{"max(stargazers_count, open_issues_count, 4)", 9},
+		{"min(stargazers_count, open_issues_count, 4)", 2},
+		{"size * 2", 84},
+		{"missing + 1", 1},
+		{"`#(type==\"Push\").size` * 10", 30},
+		{"1.5 * 2", 3},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.src, err)
+			continue
+		}
+		got, err := e.Eval(lookup)
+		if err != nil {
+			t.Errorf("Eval(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Eval(%q): expected %v, got %v", tt.src, tt.expected, got)
+		}
+	}
+}
+
+func TestEval_Now(t *testing.T) {
+	e, err := Parse("now() - parse_time(pushed_at)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	got, err := e.Eval(lookup)
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	want := time.Since(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)).Seconds()
+	if math.Abs(got-want) > 5 {
+		t.Errorf("Expected about %v, got %v", want, got)
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	for _, src := range []string{
+		"open_issues_count / 0",
+		"owner.login + 1",
+		"parse_time(stargazers_count)",
+	} {
+		e, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", src, err)
+			continue
+		}
+		if _, err := e.Eval(lookup); err == nil {
+			t.Errorf("Expected Eval(%q) to fail", src)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"stargazers_count +",
+		"(stargazers_count",
+		"stargazers_count)",
+		"unknown(1)",
+		"abs(1, 2)",
+		"max()",
+		"'unterminated",
+		"a ! b",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", src)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokPath
+	tokOp // one of + - * / % ( ) ,
+	tokInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string // operator, path, or decoded string
+	num  float64
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func isPathChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '.' || c == '#' || c == '@' || c == '\\'
+}
+
+func (l *lexer) next() token {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t' || l.src[l.pos] == '\n') {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("+-*/%(),", c) >= 0:
+		l.pos++
+		return token{kind: tokOp, text: string(c), pos: start}
+	case c == '"' || c == '\'' || c == '`':
+		end := strings.IndexByte(l.src[l.pos+1:], c)
+		if end < 0 {
+			return token{kind: tokInvalid, text: l.src[start:], pos: start}
+		}
+		text := l.src[l.pos+1 : l.pos+1+end]
+		l.pos += end + 2
+		if c == '`' {
+			return token{kind: tokPath, text: text, pos: start}
+		}
+		return token{kind: tokString, text: text, pos: start}
+	case c >= '0' && c <= '9':
+		for l.pos < len(l.src) && (l.src[l.pos] >= '0' && l.src[l.pos] <= '9' || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		// A digit followed by path characters is a path, e.g. "0.name".
+		if l.pos < len(l.src) && isPathChar(l.src[l.pos]) {
+			break
+		}
+		f, err := strconv.ParseFloat(l.src[start:l.pos], 64)
+		if err != nil {
+			return token{kind: tokInvalid, text: l.src[start:l.pos], pos: start}
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], num: f, pos: start}
+	case !isPathChar(c):
+		l.pos++
+		return token{kind: tokInvalid, text: string(c), pos: start}
+	}
+
+	for l.pos < len(l.src) && isPathChar(l.src[l.pos]) {
+		if l.src[l.pos] == '\\' {
+			l.pos++
+		}
+		l.pos++
+	}
+	if l.pos > len(l.src) {
+		l.pos = len(l.src)
+	}
+	return token{kind: tokPath, text: l.src[start:l.pos], pos: start}
+}
+
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func (p *parser) next() {
+	p.tok = p.lex.next()
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.tok.text, p.tok.pos)
+}
+
+// parseSum parses term (("+" | "-") term)*.
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseProduct parses unary (("*" | "/" | "%") unary)*.
+func (p *parser) parseProduct() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("%") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		p.next()
+		return literal{value: tok.num}, nil
+	case tok.kind == tokString:
+		p.next()
+		return literal{value: tok.text}, nil
+	case p.isOp("("):
+		p.next()
+		n, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, p.unexpected()
+		}
+		p.next()
+		return n, nil
+	case tok.kind == tokPath:
+		p.next()
+		if !p.isOp("(") {
+			return path{path: tok.text}, nil
+		}
+		return p.parseCall(tok)
+	}
+	return nil, p.unexpected()
+}
+
+// parseCall parses the arguments of the function named by tok, the opening
+// parenthesis being the current token.
+func (p *parser) parseCall(tok token) (node, error) {
+	want, ok := arity[tok.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at offset %d", tok.text, tok.pos)
+	}
+	p.next()
+
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if !p.isOp(",") {
+				return nil, p.unexpected()
+			}
+			p.next()
+		}
+		arg, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next()
+
+	if (want >= 0 && len(args) != want) || (want < 0 && len(args) == 0) {
+		return nil, fmt.Errorf("%s: wrong number of arguments (%d)", tok.text, len(args))
+	}
+	return call{name: tok.text, args: args}, nil
+}
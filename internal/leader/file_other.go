@@ -0,0 +1,25 @@
+//go:build !unix
+
+package leader
+
+import (
+	"context"
+	"errors"
+)
+
+// FileLock is not supported on this platform; use a Kubernetes Lease.
+type FileLock struct {
+	path string
+}
+
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+func (l *FileLock) TryAcquire(context.Context) (bool, error) {
+	return false, errors.ErrUnsupported
+}
+
+func (l *FileLock) Release(context.Context) error {
+	return nil
+}
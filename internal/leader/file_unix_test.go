@@ -0,0 +1,32 @@
+//go:build unix
+
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "github-exporter.lock")
+	a, b := NewFileLock(path), NewFileLock(path)
+
+	if held, err := a.TryAcquire(ctx); err != nil || !held {
+		t.Fatalf("Expected the first replica to acquire the lock, got %v, %v", held, err)
+	}
+	if held, err := a.TryAcquire(ctx); err != nil || !held {
+		t.Errorf("Expected the holder to keep the lock, got %v, %v", held, err)
+	}
+	if held, err := b.TryAcquire(ctx); err != nil || held {
+		t.Errorf("Expected the second replica to be refused, got %v, %v", held, err)
+	}
+
+	if err := a.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if held, err := b.TryAcquire(ctx); err != nil || !held {
+		t.Errorf("Expected the second replica to acquire the released lock, got %v, %v", held, err)
+	}
+}
@@ -0,0 +1,107 @@
+// Package leader elects a single active replica among exporters sharing a
+// lock, so that only one of them consumes API quota in an active/passive
+// pair.
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var logger = logging.Logger("leader")
+
+// Lock is held by at most one replica at a time.
+type Lock interface {
+	// TryAcquire acquires or renews the lock, reporting whether this
+	// replica holds it.
+	TryAcquire(ctx context.Context) (bool, error)
+	// Release gives the lock up if this replica holds it.
+	Release(ctx context.Context) error
+}
+
+// Elector keeps trying to acquire a Lock and reports leadership changes.
+// It is also a prometheus.Collector exporting github_exporter_leader.
+type Elector struct {
+	lock     Lock
+	retry    time.Duration
+	onChange func(leader bool)
+
+	leader atomic.Bool
+	desc   *prometheus.Desc
+}
+
+// NewElector returns an Elector trying lock every retry period. onChange is
+// called from Run whenever this replica gains or loses leadership.
+func NewElector(lock Lock, retry time.Duration, onChange func(leader bool)) *Elector {
+	return &Elector{
+		lock:     lock,
+		retry:    retry,
+		onChange: onChange,
+		desc: prometheus.NewDesc(
+			"github_exporter_leader",
+			"Whether this replica is the elected leader (1) or a standby serving cached metrics (0)",
+			nil, nil,
+		),
+	}
+}
+
+// Run campaigns until ctx is cancelled, then releases the lock. A replica
+// that fails to renew the lock steps down rather than risk two leaders.
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.retry)
+	defer ticker.Stop()
+
+	for {
+		held, err := e.lock.TryAcquire(ctx)
+		if err != nil {
+			logger.Error("Leader election failed", "err", err)
+		}
+		e.set(held && err == nil)
+
+		select {
+		case <-ctx.Done():
+			if e.leader.Load() {
+				release, cancel := context.WithTimeout(context.Background(), e.retry)
+				if err := e.lock.Release(release); err != nil {
+					logger.Error("Failed to release leadership", "err", err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) set(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+	if leader {
+		logger.Info("Acquired leadership, fetching from GitHub")
+	} else {
+		logger.Info("Lost leadership, serving cached metrics")
+	}
+	e.onChange(leader)
+}
+
+// IsLeader reports whether this replica currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+func (e *Elector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.desc
+}
+
+func (e *Elector) Collect(ch chan<- prometheus.Metric) {
+	var v float64
+	if e.leader.Load() {
+		v = 1
+	}
+	ch <- prometheus.MustNewConstMetric(e.desc, prometheus.GaugeValue, v)
+}
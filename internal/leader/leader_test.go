@@ -0,0 +1,78 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeLock answers TryAcquire from a script of results, repeating the last.
+type fakeLock struct {
+	mu       sync.Mutex
+	results  []error // nil means acquired, errNotHeld means held by another replica
+	released bool
+}
+
+var errNotHeld = errors.New("held by another replica")
+
+func (l *fakeLock) TryAcquire(context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.results[0]
+	if len(l.results) > 1 {
+		l.results = l.results[1:]
+	}
+	if errors.Is(err, errNotHeld) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *fakeLock) Release(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.released = true
+	return nil
+}
+
+func TestElector_Run(t *testing.T) {
+	lock := &fakeLock{results: []error{errNotHeld, nil, errors.New("api server unreachable"), nil}}
+	changes := make(chan bool, 10)
+	e := NewElector(lock, time.Millisecond, func(leader bool) { changes <- leader })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	// Acquired, lost on the error, then acquired again.
+	for _, want := range []bool{true, false, true} {
+		select {
+		case got := <-changes:
+			if got != want {
+				t.Fatalf("Expected leadership %v, got %v", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for leadership %v", want)
+		}
+	}
+	if !e.IsLeader() {
+		t.Error("Expected the elector to be leader")
+	}
+	if got := testutil.ToFloat64(e); got != 1 {
+		t.Errorf("Expected github_exporter_leader 1, got %f", got)
+	}
+
+	cancel()
+	<-done
+	if !lock.released {
+		t.Error("Expected the lock to be released on shutdown")
+	}
+}
@@ -0,0 +1,212 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// microTime is the timestamp format of Lease renewTime and acquireTime.
+const microTime = "2006-01-02T15:04:05.000000Z07:00"
+
+// Lease is a coordination.k8s.io/v1 Lease, taken through the API server
+// with the pod's service account. A holder that has not renewed it for
+// its lease duration loses it to the next candidate.
+type Lease struct {
+	url      string // of the Lease object
+	identity string
+	duration time.Duration
+	token    string
+	client   *http.Client
+}
+
+// NewInClusterLease returns the Lease name in namespace, which defaults to
+// the pod's namespace, held as identity for duration after each renewal.
+func NewInClusterLease(namespace, name, identity string, duration time.Duration) (*Lease, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster: KUBERNETES_SERVICE_HOST is not set")
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("reading service account token: %w", err)
+	}
+	if namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("reading pod namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("reading cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificate found in the cluster CA")
+	}
+
+	l := newLease("https://"+net.JoinHostPort(host, port), namespace, name, identity, duration)
+	l.token = strings.TrimSpace(string(token))
+	l.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	return l, nil
+}
+
+func newLease(apiServer, namespace, name, identity string, duration time.Duration) *Lease {
+	return &Lease{
+		url:      fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases/%s", apiServer, namespace, name),
+		identity: identity,
+		duration: duration,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type leaseObject struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"` // makes updates fail with 409 on concurrent changes
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// expired reports whether the holder of spec failed to renew it in time.
+func (s leaseSpec) expired(now time.Time) bool {
+	renewed, err := time.Parse(microTime, s.RenewTime)
+	if err != nil {
+		return true
+	}
+	return now.After(renewed.Add(time.Duration(s.LeaseDurationSeconds) * time.Second))
+}
+
+func (l *Lease) TryAcquire(ctx context.Context) (bool, error) {
+	var current leaseObject
+	status, err := l.call(ctx, http.MethodGet, l.url, nil, &current)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+
+	if status == http.StatusNotFound {
+		slash := strings.LastIndexByte(l.url, '/')
+		created := leaseObject{
+			APIVersion: "coordination.k8s.io/v1",
+			Kind:       "Lease",
+			Metadata:   leaseMetadata{Name: l.url[slash+1:]},
+			Spec:       l.spec(now, now, 0),
+		}
+		status, err = l.call(ctx, http.MethodPost, l.url[:slash], created, nil)
+		return status == http.StatusCreated, l.unexpected(status, err, http.StatusCreated)
+	}
+
+	spec := current.Spec
+	switch {
+	case spec.HolderIdentity == l.identity:
+		acquired, _ := time.Parse(microTime, spec.AcquireTime)
+		current.Spec = l.spec(acquired, now, spec.LeaseTransitions)
+	case spec.HolderIdentity == "" || spec.expired(now):
+		current.Spec = l.spec(now, now, spec.LeaseTransitions+1)
+	default:
+		return false, nil
+	}
+	status, err = l.call(ctx, http.MethodPut, l.url, current, nil)
+	return status == http.StatusOK, l.unexpected(status, err, http.StatusOK)
+}
+
+func (l *Lease) Release(ctx context.Context) error {
+	var current leaseObject
+	status, err := l.call(ctx, http.MethodGet, l.url, nil, &current)
+	if err != nil || status != http.StatusOK || current.Spec.HolderIdentity != l.identity {
+		return l.unexpected(status, err, http.StatusOK, http.StatusNotFound)
+	}
+	// Backdating the renewal lets the standby take over on its next try.
+	current.Spec.HolderIdentity = ""
+	current.Spec.RenewTime = time.Unix(0, 0).UTC().Format(microTime)
+	status, err = l.call(ctx, http.MethodPut, l.url, current, nil)
+	return l.unexpected(status, err, http.StatusOK, http.StatusConflict)
+}
+
+func (l *Lease) spec(acquired, renewed time.Time, transitions int) leaseSpec {
+	return leaseSpec{
+		HolderIdentity:       l.identity,
+		LeaseDurationSeconds: int(l.duration.Seconds()),
+		AcquireTime:          acquired.UTC().Format(microTime),
+		RenewTime:            renewed.UTC().Format(microTime),
+		LeaseTransitions:     transitions,
+	}
+}
+
+// unexpected returns err, or an error if status is none of want. A 409
+// Conflict means another replica won the race and is not an error.
+func (l *Lease) unexpected(status int, err error, want ...int) error {
+	if err != nil || status == http.StatusConflict {
+		return err
+	}
+	for _, w := range want {
+		if status == w {
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected status %d from %s", status, l.url)
+}
+
+// call sends in as JSON and decodes a 200 answer into out.
+func (l *Lease) call(ctx context.Context, method, url string, in, out any) (int, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if l.token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.token)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: %w", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, fmt.Errorf("decoding lease: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
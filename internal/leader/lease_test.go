@@ -0,0 +1,102 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPIServer stores a single Lease and enforces resourceVersion like
+// the Kubernetes API server.
+type fakeAPIServer struct {
+	mu      sync.Mutex
+	lease   *leaseObject
+	version int
+}
+
+func (s *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	const collection = "/apis/coordination.k8s.io/v1/namespaces/monitoring/leases"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == collection+"/github-exporter":
+		if s.lease == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s.lease)
+	case r.Method == http.MethodPost && r.URL.Path == collection:
+		if s.lease != nil {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.store(w, r, http.StatusCreated)
+	case r.Method == http.MethodPut && r.URL.Path == collection+"/github-exporter":
+		var obj leaseObject
+		_ = json.NewDecoder(r.Body).Decode(&obj)
+		if obj.Metadata.ResourceVersion != strconv.Itoa(s.version) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.lease = &obj
+		s.version++
+		s.lease.Metadata.ResourceVersion = strconv.Itoa(s.version)
+		_ = json.NewEncoder(w).Encode(s.lease)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeAPIServer) store(w http.ResponseWriter, r *http.Request, status int) {
+	var obj leaseObject
+	_ = json.NewDecoder(r.Body).Decode(&obj)
+	s.lease = &obj
+	s.version++
+	s.lease.Metadata.ResourceVersion = strconv.Itoa(s.version)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(s.lease)
+}
+
+func TestLease(t *testing.T) {
+	api := &fakeAPIServer{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	ctx := context.Background()
+	a := newLease(server.URL, "monitoring", "github-exporter", "pod-a", 15*time.Second)
+	b := newLease(server.URL, "monitoring", "github-exporter", "pod-b", 15*time.Second)
+
+	if held, err := a.TryAcquire(ctx); err != nil || !held {
+		t.Fatalf("Expected pod-a to create and hold the lease, got %v, %v", held, err)
+	}
+	if held, err := a.TryAcquire(ctx); err != nil || !held {
+		t.Errorf("Expected pod-a to renew the lease, got %v, %v", held, err)
+	}
+	if held, err := b.TryAcquire(ctx); err != nil || held {
+		t.Errorf("Expected pod-b to be refused while the lease is fresh, got %v, %v", held, err)
+	}
+
+	// pod-a stops renewing.
+	api.mu.Lock()
+	api.lease.Spec.RenewTime = time.Now().Add(-time.Minute).UTC().Format(microTime)
+	api.mu.Unlock()
+	if held, err := b.TryAcquire(ctx); err != nil || !held {
+		t.Fatalf("Expected pod-b to take over the expired lease, got %v, %v", held, err)
+	}
+	if api.lease.Spec.HolderIdentity != "pod-b" || api.lease.Spec.LeaseTransitions != 1 {
+		t.Errorf("Expected pod-b to hold the lease after 1 transition, got %+v", api.lease.Spec)
+	}
+
+	if err := b.Release(ctx); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if held, err := a.TryAcquire(ctx); err != nil || !held {
+		t.Errorf("Expected pod-a to acquire the released lease, got %v, %v", held, err)
+	}
+}
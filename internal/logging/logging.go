@@ -0,0 +1,130 @@
+// Package logging configures the exporter's slog output: its format, its
+// level, and the levels of individual components such as the HTTP client
+// or the scheduler. Tokens and secrets are redacted from every record.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// Components are the names accepted in per-component levels.
+var Components = []string{"collector", "http", "scheduler", "webhook", "leader", "remotewrite"}
+
+// ComponentKey is the attribute naming the component of a record.
+const ComponentKey = "component"
+
+type settings struct {
+	handler slog.Handler          // writes every record, levels are checked before
+	level   slog.Level            // level of records without a component
+	levels  map[string]slog.Level // levels of the components that override it
+}
+
+var current atomic.Pointer[settings]
+
+func init() {
+	current.Store(&settings{handler: slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})})
+}
+
+// Setup makes the default logger and every component logger write to w in
+// format, json or text. level is a level, optionally followed by
+// comma-separated component=level pairs, e.g. "info,http=debug".
+func Setup(w io.Writer, format, level string) error {
+	s := &settings{levels: make(map[string]slog.Level)}
+	for i, part := range strings.Split(level, ",") {
+		part = strings.TrimSpace(part)
+		name, value, hasName := strings.Cut(part, "=")
+		if !hasName {
+			value = name
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("log level %q: expected debug, info, warn or error", part)
+		}
+		switch {
+		case !hasName && i == 0:
+			s.level = l
+		case !hasName:
+			return fmt.Errorf("log level %q: only the first level may omit the component", part)
+		case !slices.Contains(Components, name):
+			return fmt.Errorf("log level %q: unknown component %q, expected one of %s", part, name, strings.Join(Components, ", "))
+		default:
+			s.levels[name] = l
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	switch format {
+	case "json":
+		s.handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		s.handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format %q, expected json or text", format)
+	}
+	current.Store(s)
+	slog.SetDefault(slog.New(&handler{}))
+	return nil
+}
+
+// Logger returns the logger of component. Its records carry the component
+// attribute and are filtered by the level of the component. It may be
+// created before Setup is called, e.g. in a package variable.
+func Logger(component string) *slog.Logger {
+	return slog.New(&handler{component: component})
+}
+
+// handler resolves the settings on every record, so that loggers created
+// before Setup follow it, and redacts the secrets of every record.
+type handler struct {
+	component string
+	with      []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls, in order
+}
+
+func (h *handler) level(s *settings) slog.Level {
+	if l, ok := s.levels[h.component]; ok {
+		return l
+	}
+	return s.level
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level(current.Load())
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	s := current.Load()
+	if r.Level < h.level(s) {
+		return nil
+	}
+	inner := s.handler
+	if h.component != "" {
+		inner = inner.WithAttrs([]slog.Attr{slog.String(ComponentKey, h.component)})
+	}
+	for _, with := range h.with {
+		inner = with(inner)
+	}
+	return inner.Handle(ctx, redactRecord(r))
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	attrs = slices.Clone(attrs)
+	for i := range attrs {
+		attrs[i] = redactAttr(attrs[i])
+	}
+	return h.derive(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.derive(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+func (h *handler) derive(with func(slog.Handler) slog.Handler) *handler {
+	return &handler{component: h.component, with: append(h.with[:len(h.with):len(h.with)], with)}
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetup_ComponentLevels(t *testing.T) {
+	// Created before Setup, like the package loggers.
+	httpLog := Logger("http")
+	collectorLog := Logger("collector")
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, "text", "warn,http=debug"); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+	t.Cleanup(func() { _ = Setup(os.Stdout, "json", "info") })
+
+	httpLog.Debug("api call", "url", "https://api.github.com/user")
+	collectorLog.Info("parsed metric")
+	collectorLog.With("org", "acme").Warn("discovery failed")
+	slog.Info("starting")
+	slog.Error("stopping")
+
+	out := buf.String()
+	for _, want := range []string{
+		`msg="api call" component=http url=https://api.github.com/user`,
+		`msg="discovery failed" component=collector org=acme`,
+		`msg=stopping`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"parsed metric", "starting"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("Expected %q to be filtered out, got:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestSetup_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup(&buf, "json", "debug"); err != nil {
+		t.Fatalf("Failed to set up logging: %v", err)
+	}
+	t.Cleanup(func() { _ = Setup(os.Stdout, "json", "info") })
+
+	Logger("scheduler").Debug("background fetch")
+	if got := buf.String(); !strings.Contains(got, `"component":"scheduler"`) {
+		t.Errorf("Expected a JSON record with the component, got %s", got)
+	}
+}
+
+func TestSetup_Errors(t *testing.T) {
+	for _, tt := range []struct{ format, level string }{
+		{"xml", "info"},
+		{"json", "verbose"},
+		{"json", "info,db=debug"},
+		{"json", "info,warn"},
+		{"json", "http=debug,info"},
+	} {
+		if err := Setup(&bytes.Buffer{}, tt.format, tt.level); err == nil {
+			t.Errorf("Expected an error for format %q and level %q", tt.format, tt.level)
+		}
+	}
+}
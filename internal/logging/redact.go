@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in log records.
+const Redacted = "<redacted>"
+
+// sensitiveKeys are attribute keys and header names whose values are
+// always redacted, compared in lower case.
+var sensitiveKeys = []string{"authorization", "proxy-authorization", "cookie", "set-cookie", "token", "secret", "password", "x-hub-signature", "x-hub-signature-256"}
+
+// tokenRE matches GitHub tokens and the credentials of Authorization
+// header values, wherever they appear. Credentials without a digit are
+// left alone, they are words like in "token rejected by GitHub".
+var tokenRE = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,})\b|(?i:\b(bearer|token|basic) [A-Za-z0-9._~+/=-]{16,})`)
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]bool)
+)
+
+// AddSecrets makes every log record replace values, e.g. the configured
+// tokens, wherever they appear. Empty values are ignored.
+func AddSecrets(values ...string) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	for _, v := range values {
+		if v != "" {
+			secrets[v] = true
+		}
+	}
+}
+
+// redactString replaces the secrets and tokens in s.
+func redactString(s string) string {
+	secretsMu.RLock()
+	for secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	secretsMu.RUnlock()
+	return tokenRE.ReplaceAllStringFunc(s, func(m string) string {
+		scheme, credentials, ok := strings.Cut(m, " ")
+		switch {
+		case !ok:
+			return Redacted
+		case strings.ContainsAny(credentials, "0123456789"):
+			return scheme + " " + Redacted
+		default:
+			return m
+		}
+	})
+}
+
+func sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if key == k || strings.HasSuffix(key, "_"+k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAttr scrubs a: the value of a sensitive key entirely, the secrets
+// found in other strings, errors and headers.
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if sensitive(a.Key) && a.Value.Kind() != slog.KindGroup {
+		if a.Value.Kind() == slog.KindString && a.Value.String() == "" {
+			return a
+		}
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(redactString(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			redacted[i] = redactAttr(attr)
+		}
+		a.Value = slog.GroupValue(redacted...)
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case http.Header:
+			a.Value = slog.AnyValue(redactHeader(v))
+		case error:
+			if msg := v.Error(); redactString(msg) != msg {
+				a.Value = slog.StringValue(redactString(msg))
+			}
+		}
+	}
+	return a
+}
+
+// redactHeader returns a copy of h without the values of sensitive headers.
+func redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for name, values := range h {
+		for i := range values {
+			if sensitive(name) {
+				values[i] = Redacted
+			} else {
+				values[i] = redactString(values[i])
+			}
+		}
+	}
+	return h
+}
+
+// redactRecord returns a copy of r with its message and attributes
+// scrubbed.
+func redactRecord(r slog.Record) slog.Record {
+	out := slog.NewRecord(r.Time, r.Level, redactString(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return out
+}
@@ -0,0 +1,219 @@
+// Package remotewrite pushes gathered metrics to a Prometheus remote_write
+// endpoint, for exporters nothing can scrape.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/logging"
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var logger = logging.Logger("remotewrite")
+
+// Client sends WriteRequests of the remote write 1.0 protocol: a
+// snappy-compressed protobuf message, retried with exponential backoff
+// when the endpoint is unreachable, overloaded or failing.
+type Client struct {
+	url        string
+	header     http.Header // authentication and tenant headers
+	maxRetries int
+	backoff    time.Duration // delay before the first retry, doubled on each one
+	client     *http.Client
+}
+
+func NewClient(url string, header http.Header, maxRetries int) *Client {
+	return &Client{
+		url:        url,
+		header:     header,
+		maxRetries: maxRetries,
+		backoff:    time.Second,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run pushes the metrics of gatherer every interval until ctx is cancelled.
+// Gathering runs the collectors, so requests fetched on scrape are fetched
+// on every push.
+func (c *Client) Run(ctx context.Context, gatherer prometheus.Gatherer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		families, err := gatherer.Gather()
+		if err != nil {
+			// Gather returns what it could collect along with the error.
+			logger.Warn("Some metrics could not be gathered for remote write", "err", err)
+		}
+		if err := c.Write(ctx, families, time.Now()); err != nil {
+			logger.Error("Remote write failed, the samples are dropped", "url", c.url, "err", err)
+		}
+	}
+}
+
+// Write sends families, stamping the samples without a timestamp with now.
+func (c *Client) Write(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	body := snappy.Encode(nil, encode(families, now.UnixMilli()))
+
+	delay := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, body)
+		var perm *permanentError
+		if err == nil || errors.As(err, &perm) || attempt >= c.maxRetries {
+			return err
+		}
+		logger.Debug("Remote write failed, retrying", "url", c.url, "attempt", attempt+1, "delay", delay, "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// permanentError is a rejection retrying would not change, e.g. invalid
+// samples or credentials.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (c *Client) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	for name, values := range c.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "eleboucher-github-exporter/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("status %d from %s: %s", resp.StatusCode, c.url, bytes.TrimSpace(msg))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return &permanentError{err}
+}
+
+type label struct{ name, value string }
+
+// encode marshals families as a WriteRequest. Histograms and summaries are
+// split into the series Prometheus itself would have scraped.
+func encode(families []*dto.MetricFamily, now int64) []byte {
+	var b []byte
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			ts := now
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			series := func(suffix string, value float64, extra ...label) {
+				b = appendSeries(b, name+suffix, m.GetLabel(), extra, value, ts)
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				series("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				series("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				series("", m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				h := m.GetHistogram()
+				inf := false
+				for _, bucket := range h.GetBucket() {
+					inf = inf || math.IsInf(bucket.GetUpperBound(), 1)
+					series("_bucket", float64(bucket.GetCumulativeCount()), label{"le", formatFloat(bucket.GetUpperBound())})
+				}
+				if !inf {
+					series("_bucket", float64(h.GetSampleCount()), label{"le", "+Inf"})
+				}
+				series("_sum", h.GetSampleSum())
+				series("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					series("", q.GetValue(), label{"quantile", formatFloat(q.GetQuantile())})
+				}
+				series("_sum", s.GetSampleSum())
+				series("_count", float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return b
+}
+
+// appendSeries appends a TimeSeries of a single sample to the WriteRequest
+// b. Remote write requires the labels sorted by name.
+func appendSeries(b []byte, name string, pairs []*dto.LabelPair, extra []label, value float64, ts int64) []byte {
+	labels := make([]label, 0, len(pairs)+len(extra)+1)
+	labels = append(labels, label{"__name__", name})
+	for _, p := range pairs {
+		labels = append(labels, label{p.GetName(), p.GetValue()})
+	}
+	labels = append(labels, extra...)
+	sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+
+	var series []byte
+	for _, l := range labels {
+		var lb []byte
+		lb = protowire.AppendTag(lb, 1, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.name)
+		lb = protowire.AppendTag(lb, 2, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.value)
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, lb)
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(ts))
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	return protowire.AppendBytes(b, series)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,177 @@
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type decodedSeries struct {
+	labels map[string]string
+	value  float64
+	ts     int64
+}
+
+// decode parses a WriteRequest, failing the test on malformed input.
+func decode(t *testing.T, b []byte) []decodedSeries {
+	t.Helper()
+	var out []decodedSeries
+	fields(t, b, func(_ protowire.Number, series []byte) {
+		s := decodedSeries{labels: make(map[string]string)}
+		fields(t, series, func(num protowire.Number, v []byte) {
+			switch num {
+			case 1:
+				var name, value string
+				fields(t, v, func(num protowire.Number, v []byte) {
+					if num == 1 {
+						name = string(v)
+					} else {
+						value = string(v)
+					}
+				})
+				s.labels[name] = value
+			case 2:
+				for len(v) > 0 {
+					num, typ, n := protowire.ConsumeTag(v)
+					v = v[n:]
+					switch {
+					case num == 1 && typ == protowire.Fixed64Type:
+						bits, n := protowire.ConsumeFixed64(v)
+						s.value, v = math.Float64frombits(bits), v[n:]
+					case num == 2 && typ == protowire.VarintType:
+						ts, n := protowire.ConsumeVarint(v)
+						s.ts, v = int64(ts), v[n:]
+					default:
+						t.Fatalf("Unexpected sample field %d", num)
+					}
+				}
+			}
+		})
+		out = append(out, s)
+	})
+	return out
+}
+
+func fields(t *testing.T, b []byte, fn func(protowire.Number, []byte)) {
+	t.Helper()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || typ != protowire.BytesType {
+			t.Fatalf("Unexpected field %d of type %d", num, typ)
+		}
+		b = b[n:]
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			t.Fatal("Truncated field")
+		}
+		fn(num, v)
+		b = b[n:]
+	}
+}
+
+func TestWrite_Encoding(t *testing.T) {
+	var got []decodedSeries
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Unexpected headers: %v", r.Header)
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read body: %v", err)
+		}
+		data, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Errorf("Failed to decompress body: %v", err)
+		}
+		got = decode(t, data)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	reg := prometheus.NewRegistry()
+	stars := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "gh_stars", Help: "Stars"}, []string{"repo"})
+	stars.WithLabelValues("acme/app").Set(42)
+	sizes := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "gh_pr_size", Help: "PR sizes", Buckets: []float64{10}})
+	sizes.Observe(5)
+	reg.MustRegister(stars, sizes)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather: %v", err)
+	}
+
+	c := NewClient(server.URL, http.Header{"Authorization": {"Bearer secret"}}, 0)
+	now := time.UnixMilli(1700000000000)
+	if err := c.Write(context.Background(), families, now); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+
+	want := map[string]float64{
+		`gh_stars{repo="acme/app"}`:    42,
+		`gh_pr_size_bucket{le="10"}`:   1,
+		`gh_pr_size_bucket{le="+Inf"}`: 1,
+		`gh_pr_size_sum{}`:             5,
+		`gh_pr_size_count{}`:           1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d series, got %d: %v", len(want), len(got), got)
+	}
+	for _, s := range got {
+		key := s.labels["__name__"] + "{"
+		for _, name := range []string{"le", "repo"} {
+			if v, ok := s.labels[name]; ok {
+				key += name + `="` + v + `"`
+			}
+		}
+		key += "}"
+		if v, ok := want[key]; !ok || v != s.value {
+			t.Errorf("Unexpected series %s = %v", key, s.value)
+		}
+		if s.ts != now.UnixMilli() {
+			t.Errorf("Expected timestamp %d, got %d", now.UnixMilli(), s.ts)
+		}
+	}
+}
+
+func TestWrite_Retries(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantErr  bool
+		attempts int32
+	}{
+		{"recovers", http.StatusServiceUnavailable, false, 3},
+		{"rejected", http.StatusBadRequest, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) < 3 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL, nil, 3)
+			c.backoff = time.Millisecond
+			err := c.Write(context.Background(), nil, time.Now())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got := attempts.Load(); got != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d", tt.attempts, got)
+			}
+		})
+	}
+}
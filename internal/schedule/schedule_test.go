@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Duration(t *testing.T) {
+	s, err := Parse("15m")
+	if err != nil {
+		t.Fatalf("Failed to parse duration: %v", err)
+	}
+
+	now := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	next := s.Next(now)
+	if !next.Equal(now.Add(15 * time.Minute)) {
+		t.Errorf("Expected %s, got %s", now.Add(15*time.Minute), next)
+	}
+}
+
+func TestParse_NegativeDuration(t *testing.T) {
+	if _, err := Parse("-5m"); err == nil {
+		t.Error("Expected error for negative duration, got nil")
+	}
+}
+
+func TestParse_Cron(t *testing.T) {
+	tests := []struct {
+		spec     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			spec:     "*/10 * * * *",
+			from:     time.Date(2024, 1, 15, 10, 31, 20, 0, time.UTC),
+			expected: time.Date(2024, 1, 15, 10, 40, 0, 0, time.UTC),
+		},
+		{
+			spec:     "@hourly",
+			from:     time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "30 6 * * 1-5",
+			from:     time.Date(2024, 1, 13, 12, 0, 0, 0, time.UTC), // Saturday
+			expected: time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 0 1 */3 *",
+			from:     time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "0 12 * * 7",
+			from:     time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, 1, 21, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.spec, err)
+		}
+		if next := s.Next(tt.from); !next.Equal(tt.expected) {
+			t.Errorf("%q: expected %s, got %s", tt.spec, tt.expected, next)
+		}
+	}
+}
+
+func TestParse_InvalidCron(t *testing.T) {
+	for _, spec := range []string{"", "* * *", "60 * * * *", "* * * 13 *", "*/0 * * * *", "a b c d e"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected error for %q, got nil", spec)
+		}
+	}
+}
+
+func TestNext_Unsatisfiable(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected zero time for impossible schedule, got %s", next)
+	}
+}
@@ -0,0 +1,82 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed MAJOR.MINOR.PATCH version. Missing minor or patch
+// components are treated as zero, so "3.12" parses like "3.12.0".
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string
+}
+
+// Parse accepts an optional "v" prefix and ignores build metadata.
+func Parse(s string) (Version, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+
+	var v Version
+	s, v.Prerelease, _ = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return Version{}, fmt.Errorf("invalid version %q", raw)
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", raw)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether v is lower than, equal
+// to or greater than o. A prerelease sorts before its release.
+func (v Version) Compare(o Version) int {
+	for _, d := range [][2]int{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if d[0] != d[1] {
+			if d[0] < d[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	case v.Prerelease < o.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// Number encodes v as MAJOR*1e6 + MINOR*1e3 + PATCH, so versions compare
+// as numbers, with minor and patch capped at 999. A prerelease is 0.5
+// lower than its release.
+func (v Version) Number() float64 {
+	n := float64(v.Major)*1e6 + float64(min(v.Minor, 999))*1e3 + float64(min(v.Patch, 999))
+	if v.Prerelease != "" {
+		n -= 0.5
+	}
+	return n
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
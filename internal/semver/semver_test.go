@@ -0,0 +1,76 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Version
+	}{
+		{"3.12.1", Version{Major: 3, Minor: 12, Patch: 1}},
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"3.9", Version{Major: 3, Minor: 9}},
+		{"2.0.0-rc.1+build.5", Version{Major: 2, Prerelease: "rc.1"}},
+	}
+
+	for _, tt := range tests {
+		v, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.input, err)
+		}
+		if v != tt.expected {
+			t.Errorf("%q: expected %+v, got %+v", tt.input, tt.expected, v)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{"", "v", "latest", "1.2.3.4", "1.x"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Expected error for %q, got nil", input)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"3.12.0", "3.9.0", 1},
+		{"3.9", "3.9.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.0.0-beta", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-beta", -1},
+	}
+
+	for _, tt := range tests {
+		a, _ := Parse(tt.a)
+		b, _ := Parse(tt.b)
+		if got := a.Compare(b); got != tt.expected {
+			t.Errorf("Compare(%s, %s): expected %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"v1.2.3", 1002003},
+		{"3.12", 3012000},
+		{"v2.0.0-rc.1", 1999999.5},
+		{"0.1.1000", 1999},
+	}
+
+	for _, tt := range tests {
+		v, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+		}
+		if got := v.Number(); got != tt.expected {
+			t.Errorf("Number(%s): expected %f, got %f", tt.input, tt.expected, got)
+		}
+	}
+}
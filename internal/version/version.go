@@ -0,0 +1,71 @@
+// Package version holds the build metadata injected with -ldflags, e.g.
+//
+//	go build -ldflags "-X github.com/eleboucher/github-exporter/internal/version.Version=v1.2.3"
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = "unknown"
+)
+
+// Revision returns Commit, falling back to the VCS revision the Go
+// toolchain stamps into builds made from a checkout.
+func Revision() string {
+	if Commit != "" {
+		return Commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// String describes the build on a single line.
+func String() string {
+	return fmt.Sprintf("github-exporter %s (revision %s, built %s, %s)", Version, Revision(), Date, runtime.Version())
+}
+
+// Collector exports github_exporter_build_info, a constant 1 labelled with
+// the build metadata and the hash of the active configuration, which
+// configHash returns on every scrape since a reload may change it.
+func Collector(configHash func() string) prometheus.Collector {
+	return &buildInfo{
+		desc: prometheus.NewDesc(
+			"github_exporter_build_info",
+			"Build metadata of the exporter, always 1",
+			[]string{"config_hash"},
+			prometheus.Labels{
+				"version":   Version,
+				"revision":  Revision(),
+				"goversion": runtime.Version(),
+			},
+		),
+		configHash: configHash,
+	}
+}
+
+type buildInfo struct {
+	desc       *prometheus.Desc
+	configHash func() string
+}
+
+func (b *buildInfo) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.desc
+}
+
+func (b *buildInfo) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(b.desc, prometheus.GaugeValue, 1, b.configHash())
+}
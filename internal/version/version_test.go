@@ -0,0 +1,33 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	Version, Commit = "v1.2.3", "abc123"
+	defer func() { Version, Commit = "dev", "" }()
+
+	expected := `
+# HELP github_exporter_build_info Build metadata of the exporter, always 1
+# TYPE github_exporter_build_info gauge
+github_exporter_build_info{config_hash="f00d",goversion="` + runtime.Version() + `",revision="abc123",version="v1.2.3"} 1
+`
+	hash := func() string { return "f00d" }
+	if err := testutil.CollectAndCompare(Collector(hash), strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestString(t *testing.T) {
+	Version, Commit = "v1.2.3", "abc123"
+	defer func() { Version, Commit = "dev", "" }()
+
+	if got := String(); !strings.Contains(got, "v1.2.3") || !strings.Contains(got, "abc123") {
+		t.Errorf("Expected version and revision in %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+// Package web serves the exporter's HTTP endpoints, optionally behind TLS
+// and basic auth configured with a web config file. The file uses the
+// layout of the Prometheus exporter-toolkit:
+//
+//	tls_server_config:
+//	  cert_file: server.crt
+//	  key_file: server.key
+//	  client_auth_type: RequireAndVerifyClientCert
+//	  client_ca_file: ca.crt
+//	basic_auth_users:
+//	  prometheus: <sha256 hex digest of the password>
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the web config file.
+type Config struct {
+	TLSServerConfig *TLSConfig        `yaml:"tls_server_config"`
+	BasicAuthUsers  map[string]string `yaml:"basic_auth_users"` // user -> sha256 hex digest of the password
+}
+
+type TLSConfig struct {
+	CertFile       string `yaml:"cert_file"`
+	KeyFile        string `yaml:"key_file"`
+	ClientAuthType string `yaml:"client_auth_type"`
+	ClientCAFile   string `yaml:"client_ca_file"`
+	MinVersion     string `yaml:"min_version"` // TLS12 or TLS13, defaults to TLS12
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+var tlsVersions = map[string]uint16{
+	"":      tls.VersionTLS12,
+	"TLS12": tls.VersionTLS12,
+	"TLS13": tls.VersionTLS13,
+}
+
+// LoadConfig reads and checks the web config file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	for user, digest := range cfg.BasicAuthUsers {
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("basic_auth_users: password of %q must be a sha256 hex digest", user)
+		}
+	}
+	if cfg.TLSServerConfig != nil {
+		if _, err := cfg.TLSServerConfig.tlsConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+// tlsConfig builds the server TLS configuration.
+func (c *TLSConfig) tlsConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, fmt.Errorf("tls_server_config: cert_file and key_file are required")
+	}
+	clientAuth, ok := clientAuthTypes[c.ClientAuthType]
+	if !ok {
+		return nil, fmt.Errorf("tls_server_config: unknown client_auth_type %q", c.ClientAuthType)
+	}
+	minVersion, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("tls_server_config: unknown min_version %q, expected TLS12 or TLS13", c.MinVersion)
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls_server_config: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   clientAuth,
+		MinVersion:   minVersion,
+	}
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls_server_config: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_server_config: no certificate found in %s", c.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+	} else if strings.Contains(c.ClientAuthType, "Verify") {
+		return nil, fmt.Errorf("tls_server_config: client_ca_file is required with client_auth_type %s", c.ClientAuthType)
+	}
+	return cfg, nil
+}
+
+// Handler wraps next with basic auth when users are configured.
+func (c *Config) Handler(next http.Handler) http.Handler {
+	if len(c.BasicAuthUsers) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || !c.authenticate(user, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="github-exporter"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (c *Config) authenticate(user, password string) bool {
+	digest, ok := c.BasicAuthUsers[user]
+	if !ok {
+		return false
+	}
+	want, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	got := sha256.Sum256([]byte(password))
+	return subtle.ConstantTimeCompare(got[:], want) == 1
+}
+
+// NewServer returns a server for handler set up with the web config at
+// configPath, so that an invalid file is reported before listening.
+func NewServer(handler http.Handler, configPath string) (*http.Server, error) {
+	if configPath == "" {
+		return &http.Server{Handler: handler}, nil
+	}
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading web config: %w", err)
+	}
+
+	server := &http.Server{Handler: cfg.Handler(handler)}
+	if cfg.TLSServerConfig != nil {
+		if server.TLSConfig, err = cfg.TLSServerConfig.tlsConfig(); err != nil {
+			return nil, fmt.Errorf("loading web config: %w", err)
+		}
+	}
+	return server, nil
+}
+
+// Serve accepts connections on ln with a server from NewServer, over TLS
+// when the web config enables it.
+func Serve(server *http.Server, ln net.Listener) error {
+	if server.TLSConfig != nil {
+		return server.ServeTLS(ln, "", "")
+	}
+	return server.Serve(ln)
+}
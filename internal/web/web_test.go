@@ -0,0 +1,91 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "web.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write web config: %v", err)
+	}
+	return path
+}
+
+func TestHandler_BasicAuth(t *testing.T) {
+	digest := sha256.Sum256([]byte("secret"))
+	cfg, err := LoadConfig(writeConfig(t, "basic_auth_users:\n  prometheus: "+hex.EncodeToString(digest[:])+"\n"))
+	if err != nil {
+		t.Fatalf("Failed to load web config: %v", err)
+	}
+
+	handler := cfg.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		user, password string
+		expected       int
+	}{
+		{"prometheus", "secret", http.StatusOK},
+		{"prometheus", "wrong", http.StatusUnauthorized},
+		{"other", "secret", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.SetBasicAuth(tt.user, tt.password)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.expected {
+			t.Errorf("Expected %d for %s:%s, got %d", tt.expected, tt.user, tt.password, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", rec.Code)
+	}
+}
+
+func TestLoadConfig_InvalidDigest(t *testing.T) {
+	if _, err := LoadConfig(writeConfig(t, "basic_auth_users:\n  prometheus: secret\n")); err == nil {
+		t.Error("Expected error for a plain-text password")
+	}
+}
+
+func TestLoadConfig_TLSRequiresCertAndKey(t *testing.T) {
+	if _, err := LoadConfig(writeConfig(t, "tls_server_config:\n  cert_file: server.crt\n")); err == nil {
+		t.Error("Expected error without key_file")
+	}
+}
+
+func TestLoadConfig_UnknownClientAuthType(t *testing.T) {
+	content := "tls_server_config:\n  cert_file: server.crt\n  key_file: server.key\n  client_auth_type: Sometimes\n"
+	if _, err := LoadConfig(writeConfig(t, content)); err == nil {
+		t.Error("Expected error for an unknown client_auth_type")
+	}
+}
+
+func TestNewServer_MissingCertificate(t *testing.T) {
+	path := writeConfig(t, "tls_server_config:\n  cert_file: missing.crt\n  key_file: missing.key\n")
+	if _, err := NewServer(http.NotFoundHandler(), path); err == nil {
+		t.Error("Expected error for a missing certificate before listening")
+	}
+}
+
+func TestNewServer_WithoutConfig(t *testing.T) {
+	server, err := NewServer(http.NotFoundHandler(), "")
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	if server.TLSConfig != nil {
+		t.Error("Expected a plain HTTP server without a web config")
+	}
+}
@@ -0,0 +1,129 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/eleboucher/github-exporter/internal/logging"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+var logger = logging.Logger("webhook")
+
+// maxPayloadBytes matches the largest payload GitHub delivers.
+const maxPayloadBytes = 25 << 20
+
+// Receiver turns GitHub webhook deliveries into metrics. It is both the
+// HTTP handler for the webhook endpoint and a prometheus.Collector.
+type Receiver struct {
+	secret []byte
+
+	events           *prometheus.CounterVec
+	invalid          prometheus.Counter
+	workflowDuration *prometheus.HistogramVec
+}
+
+func NewReceiver(secret string) *Receiver {
+	return &Receiver{
+		secret: []byte(secret),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "github_webhook_events_total",
+			Help: "Webhook deliveries received from GitHub",
+		}, []string{"event", "action", "repo"}),
+		invalid: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "github_webhook_invalid_signatures_total",
+			Help: "Webhook deliveries rejected because of a missing or invalid signature",
+		}),
+		workflowDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "github_webhook_workflow_run_duration_seconds",
+			Help:    "Duration of completed workflow runs reported through webhooks",
+			Buckets: []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200},
+		}, []string{"repo", "workflow", "conclusion"}),
+	}
+}
+
+func (r *Receiver) Describe(ch chan<- *prometheus.Desc) {
+	r.events.Describe(ch)
+	r.invalid.Describe(ch)
+	r.workflowDuration.Describe(ch)
+}
+
+func (r *Receiver) Collect(ch chan<- prometheus.Metric) {
+	r.events.Collect(ch)
+	r.invalid.Collect(ch)
+	r.workflowDuration.Collect(ch)
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPayloadBytes))
+	if err != nil {
+		logger.Error("Error reading webhook payload", "err", err)
+		http.Error(w, "unable to read payload", http.StatusBadRequest)
+		return
+	}
+
+	if !r.validSignature(req.Header.Get("X-Hub-Signature-256"), body) {
+		r.invalid.Inc()
+		logger.Warn("Rejected webhook delivery with invalid signature", "delivery", req.Header.Get("X-GitHub-Delivery"))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	event := req.Header.Get("X-GitHub-Event")
+	payload := gjson.ParseBytes(body)
+	action := payload.Get("action").String()
+	repo := payload.Get("repository.full_name").String()
+
+	r.events.WithLabelValues(event, action, repo).Inc()
+	logger.Debug("Received webhook", "event", event, "action", action, "repo", repo)
+
+	if event == "workflow_run" && action == "completed" {
+		r.observeWorkflowRun(repo, payload.Get("workflow_run"))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validSignature checks the X-Hub-Signature-256 header, an HMAC-SHA256 of
+// the payload keyed with the webhook secret.
+func (r *Receiver) validSignature(header string, body []byte) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+func (r *Receiver) observeWorkflowRun(repo string, run gjson.Result) {
+	started, err := time.Parse(time.RFC3339, run.Get("run_started_at").String())
+	if err != nil {
+		return
+	}
+	finished, err := time.Parse(time.RFC3339, run.Get("updated_at").String())
+	if err != nil || finished.Before(started) {
+		return
+	}
+
+	r.workflowDuration.
+		WithLabelValues(repo, run.Get("name").String(), run.Get("conclusion").String()).
+		Observe(finished.Sub(started).Seconds())
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func deliver(r *Receiver, event, signature, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", signature)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeHTTP_ValidSignature(t *testing.T) {
+	r := NewReceiver("s3cret")
+	body := `{"action": "opened", "repository": {"full_name": "acme/api"}}`
+
+	rec := deliver(r, "pull_request", sign("s3cret", body), body)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+
+	got := testutil.ToFloat64(r.events.WithLabelValues("pull_request", "opened", "acme/api"))
+	if got != 1 {
+		t.Errorf("Expected 1 event, got %f", got)
+	}
+}
+
+func TestServeHTTP_InvalidSignature(t *testing.T) {
+	r := NewReceiver("s3cret")
+	body := `{"action": "opened", "repository": {"full_name": "acme/api"}}`
+
+	for _, signature := range []string{"", "sha256=deadbeef", sign("wrong", body)} {
+		rec := deliver(r, "pull_request", signature, body)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for signature %q, got %d", signature, rec.Code)
+		}
+	}
+
+	if got := testutil.ToFloat64(r.invalid); got != 3 {
+		t.Errorf("Expected 3 invalid deliveries, got %f", got)
+	}
+	if got := testutil.CollectAndCount(r.events); got != 0 {
+		t.Errorf("Expected no events to be counted, got %d", got)
+	}
+}
+
+func TestServeHTTP_WorkflowRunDuration(t *testing.T) {
+	r := NewReceiver("s3cret")
+	body := `{
+		"action": "completed",
+		"repository": {"full_name": "acme/api"},
+		"workflow_run": {
+			"name": "CI",
+			"conclusion": "success",
+			"run_started_at": "2024-01-15T10:00:00Z",
+			"updated_at": "2024-01-15T10:05:00Z"
+		}
+	}`
+
+	rec := deliver(r, "workflow_run", sign("s3cret", body), body)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d", rec.Code)
+	}
+
+	if got := testutil.CollectAndCount(r.workflowDuration); got != 1 {
+		t.Errorf("Expected 1 histogram series, got %d", got)
+	}
+}
+
+func TestServeHTTP_MethodNotAllowed(t *testing.T) {
+	r := NewReceiver("s3cret")
+	req := httptest.NewRequest(http.MethodGet, "/webhook", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+}